@@ -0,0 +1,114 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/triggers"
+	"github.com/getsolus/usysconf/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// Audit fulfills the "audit" subcommand
+var Audit = cmd.CMD{
+	Name:  "audit",
+	Alias: "au",
+	Short: "Check the ownership and permissions of the trigger files, since they are run as root",
+	Args:  &AuditArgs{},
+	Flags: &AuditFlags{},
+	Run:   AuditRun,
+}
+
+// AuditArgs contains the arguments for the "audit" subcommand
+type AuditArgs struct{}
+
+// AuditFlags contains the additional flags for the "audit" subcommand
+type AuditFlags struct {
+	Strict bool `short:"S" long:"strict" desc:"Exit with code 1 when any problems are found"`
+}
+
+// AuditRun warns about trigger files which could be tampered with by other users
+func AuditRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	// args := c.Args.(*AuditArgs)
+	flags := c.Flags.(*AuditFlags)
+
+	// Enable Debug Output
+	if gFlags.Debug || gFlags.Verbosity >= triggers.VerbosityDebug {
+		log.SetLevel(level.Debug)
+	}
+	// Load Triggers
+	tm, err := loadAll(gFlags)
+	if err != nil {
+		log.Fatalf("Failed to load triggers, reason: %s\n", err)
+	}
+	// Home triggers may also be owned by the owner of the home directory
+	var homeDir string
+	var homeOwner uint32
+	if home, err := config.HomeDir(); err == nil && len(home) > 0 {
+		if info, err := os.Stat(home); err == nil {
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+				homeDir, homeOwner = config.HomeTriggers(home), stat.Uid
+			}
+		}
+	}
+	// Audit every trigger file, and the directories they were found in
+	paths := make(map[string]bool)
+	for _, t := range tm {
+		path := t.Path
+		if len(t.Archive) > 0 {
+			path = t.Archive
+		}
+		paths[path] = true
+		paths[filepath.Dir(path)] = true
+	}
+	var sorted []string
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	found := 0
+	for _, path := range sorted {
+		owners := []uint32{0}
+		if len(homeDir) > 0 && (path == homeDir || strings.HasPrefix(path, homeDir+"/")) {
+			owners = append(owners, homeOwner)
+		}
+		problems, err := util.AuditPath(path, owners...)
+		if err != nil {
+			log.Errorf("%s: failed to check, reason: %s\n", path, err)
+			found++
+			continue
+		}
+		if len(problems) > 0 {
+			log.Warnf("%s: %s\n", path, strings.Join(problems, ", "))
+			found += len(problems)
+		}
+	}
+	if found == 0 {
+		log.Goodf("Checked %d path(s), no problems found\n", len(sorted))
+		return
+	}
+	log.Warnf("Checked %d path(s), found %d problem(s)\n", len(sorted), found)
+	if flags.Strict {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/triggers"
+	"os"
+	"sort"
+	"time"
+)
+
+// Bench fulfills the "bench" subcommand
+var Bench = cmd.CMD{
+	Name:  "bench",
+	Alias: "b",
+	Short: "Run a trigger repeatedly, reporting how long each of its bins took, its removals are repeated on every run",
+	Flags: &BenchFlags{Runs: 10},
+	Args:  &BenchArgs{},
+	Run:   BenchRun,
+}
+
+// BenchFlags contains the additional flags for the "bench" subcommand
+type BenchFlags struct {
+	Runs int64 `short:"r" long:"runs" desc:"Number of times to run the trigger (default: 10)"`
+	Wait bool  `short:"W" long:"wait" desc:"Wait for another running instance to finish, instead of exiting"`
+}
+
+// BenchArgs contains the arguments for the "bench" subcommand
+type BenchArgs struct {
+	Name string `desc:"Name of the trigger to benchmark"`
+}
+
+// BenchRun runs a trigger several times and prints statistics for the durations of its bins. Each
+// run is a real one, removing its paths and running its coalesced bins every time, while holding
+// the state lock like any other run.
+func BenchRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*BenchArgs)
+	flags := c.Flags.(*BenchFlags)
+
+	// Enable Debug Output
+	if gFlags.Debug {
+		log.SetLevel(level.Debug)
+	}
+	// Root user check
+	if os.Geteuid() != 0 {
+		log.Fatalln("You must have root privileges to run triggers")
+	}
+	if flags.Runs < 1 {
+		log.Fatalln("The number of runs must be at least 1")
+	}
+	// Prevent colliding with a concurrent run
+	lock, err := state.Lock(flags.Wait)
+	if err != nil {
+		log.Fatalf("Failed to lock state, reason: %s\n", err)
+	}
+	defer state.Unlock(lock)
+	// Load Triggers
+	tm, err := loadAll(gFlags)
+	if err != nil {
+		log.Fatalf("Failed to load triggers, reason: %s\n", err)
+	}
+	base, ok := tm[args.Name]
+	if !ok {
+		log.Fatalf("Could not find trigger %s\n", args.Name)
+	}
+	if base.RemoveDirs != nil {
+		log.Warnf("The paths of '%s' will be removed on each of the %d runs\n", base.Name, flags.Runs)
+	}
+	// Run the trigger on its own, regardless of the triggers it runs after
+	base.RunAfterChanged = nil
+	s := detectScope(gFlags, triggers.Scope{
+		Chroot: gFlags.Chroot,
+		Debug:  gFlags.Debug,
+		Forced: true,
+		Live:   gFlags.Live,
+
+		DefaultTimeout: defaultTimeout(gFlags),
+	})
+	// Run the trigger, from a clean state each time
+	var tasks []string
+	durations := make(map[string][]time.Duration)
+	for i := int64(0); i < flags.Runs; i++ {
+		// The coalesced bins fill in the outputs of the trigger which requested them
+		results := triggers.RunWithState(triggers.Map{base.Name: base}, s, []string{base.Name}, make(state.Map), make(state.Map))
+		for _, out := range results[0].Output {
+			if len(out.Name) == 0 {
+				continue
+			}
+			task := out.Name
+			if len(out.SubTask) > 0 {
+				task = fmt.Sprintf("%s (%s)", out.Name, out.SubTask)
+			}
+			if _, ok := durations[task]; !ok {
+				tasks = append(tasks, task)
+			}
+			durations[task] = append(durations[task], out.Duration)
+		}
+	}
+	// Print statistics
+	if gFlags.Width > 0 {
+		triggers.TaskWidth = int(gFlags.Width)
+	}
+	label := func(task string) string {
+		return triggers.Output{Name: task}.Label()
+	}
+	log.Printf("\n%s %12s %12s %12s %12s\n", label("Task"), "Min", "Median", "Max", "Mean")
+	for _, task := range tasks {
+		ds := durations[task]
+		sort.Slice(ds, func(i, j int) bool {
+			return ds[i] < ds[j]
+		})
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+		}
+		median := ds[len(ds)/2]
+		if len(ds)%2 == 0 {
+			median = (ds[len(ds)/2-1] + median) / 2
+		}
+		mean := total / time.Duration(len(ds))
+		log.Printf("%s %12s %12s %12s %12s\n", label(task), ds[0].Round(time.Microsecond), median.Round(time.Microsecond),
+			ds[len(ds)-1].Round(time.Microsecond), mean.Round(time.Microsecond))
+	}
+	log.Println()
+}
@@ -0,0 +1,50 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/config"
+)
+
+// Cache fulfills the "cache" subcommand
+var Cache = cmd.CMD{
+	Name:  "cache",
+	Alias: "ca",
+	Short: "Manage the cache of parsed triggers, i.e. \"cache clear\"",
+	Args:  &CacheArgs{},
+	Run:   CacheRun,
+}
+
+// CacheArgs contains the arguments for the "cache" subcommand
+type CacheArgs struct {
+	Action string `desc:"Action to perform on the cache (clear)"`
+}
+
+// CacheRun performs an action on the parse cache
+func CacheRun(r *cmd.RootCMD, c *cmd.CMD) {
+	// gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*CacheArgs)
+	switch args.Action {
+	case "clear":
+		if err := config.ClearCache(); err != nil {
+			log.Fatalf("Failed to clear the cache, reason: %s\n", err)
+		}
+		log.Goodln("Cleared the cache")
+	default:
+		log.Fatalf("Unsupported cache action '%s'\n", args.Action)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"os"
+)
+
+// Config fulfills the "config" subcommand
+var Config = cmd.CMD{
+	Name:  "config",
+	Alias: "cfg",
+	Short: "Inspect trigger configurations, i.e. \"config show <name>\" prints the effective configuration",
+	Args:  &ConfigArgs{},
+	Run:   ConfigRun,
+}
+
+// ConfigArgs contains the arguments for the "config" subcommand
+type ConfigArgs struct {
+	Action string `desc:"Action to perform (show)"`
+	Name   string `desc:"Name of the trigger"`
+}
+
+// ConfigRun carries out an action for the configuration of a trigger
+func ConfigRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*ConfigArgs)
+
+	// Enable Debug Output
+	if gFlags.Debug {
+		log.SetLevel(level.Debug)
+	}
+	if args.Action != "show" {
+		log.Fatalf("Unsupported action '%s'\n", args.Action)
+	}
+	// Keep the configuration alone on stdout
+	log.SetOutput(os.Stderr)
+	// Load Triggers
+	tm, err := loadAll(gFlags)
+	if err != nil {
+		log.Fatalf("Failed to load triggers, reason: %s\n", err)
+	}
+	t, ok := tm[args.Name]
+	if !ok {
+		log.Fatalf("Could not find trigger %s\n", args.Name)
+	}
+	// Print configuration
+	fmt.Printf("# %s\n", t.Path)
+	if err = toml.NewEncoder(os.Stdout).Encode(t); err != nil {
+		log.Fatalf("Failed to print configuration, reason: %s\n", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/triggers"
+	"os"
+	"sort"
+)
+
+// Doctor fulfills the "doctor" subcommand
+var Doctor = cmd.CMD{
+	Name:  "doctor",
+	Alias: "dr",
+	Short: "Diagnose the environment, explaining why triggers may not be running",
+	Args:  &DoctorArgs{},
+	Run:   DoctorRun,
+}
+
+// DoctorArgs contains the arguments for the "doctor" subcommand
+type DoctorArgs struct{}
+
+// DoctorRun reports on the scope, privileges and trigger configurations
+func DoctorRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	// args := c.Args.(*DoctorArgs)
+
+	// Enable Debug Output
+	if gFlags.Debug {
+		log.SetLevel(level.Debug)
+	}
+
+	// Privileges
+	if os.Geteuid() == 0 {
+		log.Goodln("Running as root")
+	} else {
+		log.Warnln("Not running as root, triggers may only be run with --dry-run")
+	}
+
+	// Scope, as it would be detected by "run"
+	s := detectScope(gFlags, triggers.Scope{
+		Chroot: gFlags.Chroot,
+		Debug:  gFlags.Debug,
+		Live:   gFlags.Live,
+	})
+	if len(gFlags.AssumeScope) > 0 {
+		log.Warnf("Assumed scope: chroot=%t live=%t, not detected\n", s.Chroot, s.Live)
+	} else {
+		log.Infof("Detected scope: chroot=%t live=%t\n", s.Chroot, s.Live)
+	}
+
+	setLoading(gFlags)
+	if config.Signatures != config.SignaturesOff {
+		log.Infof("Signatures: %s, verified with '%s'\n", config.Signatures, config.TrustKey)
+	}
+	// Trigger directories
+	dirs := []string{config.SysDir, config.UsrDir}
+	home, err := config.HomeDir()
+	switch {
+	case err != nil:
+		log.Warnf("Failed to find home directory, reason: %s\n", err)
+	case len(home) == 0:
+		log.Infoln("Home triggers are not loaded when running as root without sudo")
+	default:
+		dirs = append(dirs, config.HomeTriggers(home))
+	}
+	total, failed := 0, 0
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			log.Infof("%s: not available, reason: %s\n", dir, err)
+			continue
+		}
+		tm, failures := config.LoadEach(dir)
+		log.Infof("%s: %d trigger(s) loaded\n", dir, len(tm))
+		for _, failure := range failures {
+			log.Errorf("    %s\n", failure)
+		}
+		total += len(tm)
+		failed += len(failures)
+	}
+	if len(gFlags.TriggerArchive) > 0 {
+		tm, failures := config.LoadArchive(gFlags.TriggerArchive)
+		log.Infof("%s: %d trigger(s) loaded\n", gFlags.TriggerArchive, len(tm))
+		for _, failure := range failures {
+			log.Errorf("    %s\n", failure)
+		}
+		total += len(tm)
+		failed += len(failures)
+	}
+	if failed > 0 {
+		log.Errorf("Found %d trigger(s), %d failed to load\n", total, failed)
+		os.Exit(1)
+	}
+	log.Goodf("Found %d trigger(s), all loaded\n", total)
+	// References between triggers, across every directory
+	tm, err := loadAll(gFlags)
+	if err != nil {
+		log.Fatalf("Failed to load triggers, reason: %s\n", err)
+	}
+	if errs := tm.Unresolved(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorf("    %s\n", err)
+		}
+		log.Errorf("Found %d reference(s) to unknown triggers\n", len(errs))
+		os.Exit(1)
+	}
+	log.Goodln("All references between triggers resolved")
+	// Arguments written for a shell, which "run --strict" warns about
+	names := make([]string, 0, len(tm))
+	for name := range tm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	suspicious := 0
+	for _, name := range names {
+		t := tm[name]
+		for _, finding := range t.LintArgs() {
+			log.Warnf("    %s\n", finding)
+			suspicious++
+		}
+	}
+	if suspicious > 0 {
+		log.Warnf("Found %d argument(s) which look like they were written for a shell\n", suspicious)
+	} else {
+		log.Goodln("No arguments look like they were written for a shell")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"strings"
+)
+
+// Expand fulfills the "expand" subcommand
+var Expand = cmd.CMD{
+	Name:  "expand",
+	Alias: "ex",
+	Short: "Print the invocations generated for the bins of a trigger, given the current filesystem",
+	Args:  &ExpandArgs{},
+	Run:   ExpandRun,
+}
+
+// ExpandArgs contains the arguments for the "expand" subcommand
+type ExpandArgs struct {
+	Name string `desc:"Name of the trigger"`
+}
+
+// ExpandRun prints the fan-out of every bin in a trigger
+func ExpandRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*ExpandArgs)
+
+	// Enable Debug Output
+	if gFlags.Debug {
+		log.SetLevel(level.Debug)
+	}
+	// Load Triggers
+	tm, err := loadAll(gFlags)
+	if err != nil {
+		log.Fatalf("Failed to load triggers, reason: %s\n", err)
+	}
+	t, ok := tm[args.Name]
+	if !ok {
+		log.Fatalf("Could not find trigger %s\n", args.Name)
+	}
+	// Print the invocations of each bin
+	for _, b := range t.Bins {
+		b.Args = t.FillPlaceholders(b.Args)
+		bins, outputs := b.FanOut()
+		log.Printf("\n%s: %d invocation(s)\n", b.Task, len(bins))
+		for i, nb := range bins {
+			line := strings.TrimSpace(nb.Bin + " " + strings.Join(nb.Args, " "))
+			if len(outputs[i].SubTask) > 0 {
+				line += "    (" + b.Replace.EnvName() + "=" + outputs[i].SubTask + ")"
+			}
+			log.Printf("    %s\n", line)
+		}
+	}
+	log.Println()
+}
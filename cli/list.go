@@ -18,7 +18,6 @@ import (
 	"github.com/DataDrake/cli-ng/cmd"
 	log "github.com/DataDrake/waterlog"
 	"github.com/DataDrake/waterlog/level"
-	"github.com/getsolus/usysconf/config"
 	"github.com/getsolus/usysconf/triggers"
 )
 
@@ -27,10 +26,24 @@ var List = cmd.CMD{
 	Name:  "list",
 	Alias: "ls",
 	Short: "List available triggers to run (user-specific)",
+	Flags: &ListFlags{Tree: noTree},
 	Args:  &ListArgs{},
 	Run:   ListRun,
 }
 
+// noTree is the default for ListFlags.Tree, since "--tree" on its own sets an empty string
+const noTree = "none"
+
+// ListFlags contains the additional flags for the "list" subcommand
+type ListFlags struct {
+	Verbose bool `short:"v" long:"verbose"              desc:"Include the tasks and descriptions of each bin"`
+	Reasons bool `short:"r" long:"list-skipped-reasons" desc:"Include the declared skip and check conditions of each trigger"`
+
+	Plain    bool   `long:"no-table"  desc:"Print each trigger as \"name - description\", rather than as a table"`
+	MaxWidth int64  `long:"max-width" desc:"Widest a column of the table may be before it is truncated (default: 60)"`
+	Tree     string `long:"tree"      desc:"Group the triggers by tag (the default) or phase, i.e. --tree=phase"`
+}
+
 // ListArgs contains the arguments for the "list" subcommand
 type ListArgs struct{}
 
@@ -38,17 +51,35 @@ type ListArgs struct{}
 func ListRun(r *cmd.RootCMD, c *cmd.CMD) {
 	gFlags := r.Flags.(*GlobalFlags)
 	// args := c.Args.(*ListArgs)
+	flags := c.Flags.(*ListFlags)
 
 	// Enable Debug Output
-	if gFlags.Debug {
+	if gFlags.Debug || gFlags.Verbosity >= triggers.VerbosityDebug {
 		log.SetLevel(level.Debug)
 	}
 	// Load Triggers
-	tm, err := config.LoadAll()
+	tm, err := loadAll(gFlags)
 	if err != nil {
 		log.Fatalf("Failed to load triggers, reason: %s\n", err)
 	}
+	tree := ""
+	switch flags.Tree {
+	case noTree:
+	case "", triggers.TreeTag:
+		tree = triggers.TreeTag
+	case triggers.TreePhase:
+		tree = triggers.TreePhase
+	default:
+		log.Fatalf("Invalid tree '%s', must be %s or %s\n", flags.Tree, triggers.TreeTag, triggers.TreePhase)
+	}
 	// Print triggers
 	log.Info("Available triggers:\n\n")
-	triggers.Print(tm)
+	triggers.Print(tm, triggers.PrintOptions{
+		Verbose: flags.Verbose || gFlags.Verbosity >= triggers.VerbosityCommands,
+		Gating:  flags.Reasons || gFlags.Verbosity >= triggers.VerbosityOutput,
+
+		Plain:    flags.Plain,
+		MaxWidth: int(flags.MaxWidth),
+		Tree:     tree,
+	})
 }
@@ -0,0 +1,122 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/triggers"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skeleton is the outline of a new trigger, with the name filled in
+const skeleton = `# Trigger: %s
+description = "Describe what this trigger does"
+
+# Bins are run in order, each one is a separate command
+[[bins]]
+task = "Describe this step"
+bin = "/usr/bin/true"
+args = []
+
+# Replace the "***" argument with each matching path, running the bin once per path
+#   args = ["***"]
+#   [bins.replace]
+#   paths = ["/usr/share/example/*"]
+#   exclude = []
+
+# The trigger only runs when these paths exist and have changed since the last run
+[check]
+paths = ["/usr/share/example"]
+
+# Never run the trigger in these cases
+# [skip]
+# chroot = true
+# live = true
+# paths = ["/etc/example/disabled"]
+
+# Paths to delete before running the bins
+# [remove]
+# paths = ["/var/cache/example/*"]
+# exclude = []
+`
+
+// New fulfills the "new" subcommand
+var New = cmd.CMD{
+	Name:  "new",
+	Alias: "n",
+	Short: "Create a new trigger from a template, in the system directory",
+	Flags: &NewFlags{},
+	Args:  &NewArgs{},
+	Run:   NewRun,
+}
+
+// NewFlags contains the additional flags for the "new" subcommand
+type NewFlags struct {
+	Force bool   `short:"f" long:"force" desc:"Overwrite the trigger if it already exists"`
+	Dir   string `short:"D" long:"dir"   desc:"Directory to create the trigger in (default: the system directory)"`
+}
+
+// NewArgs contains the arguments for the "new" subcommand
+type NewArgs struct {
+	Name string `desc:"Name of the trigger to create"`
+}
+
+// NewRun writes out a new trigger
+func NewRun(r *cmd.RootCMD, c *cmd.CMD) {
+	gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*NewArgs)
+	flags := c.Flags.(*NewFlags)
+
+	// Enable Debug Output
+	if gFlags.Debug {
+		log.SetLevel(level.Debug)
+	}
+	// The name becomes the file name, so it must not lead outside of the directory
+	if len(args.Name) == 0 || strings.ContainsRune(args.Name, filepath.Separator) || strings.Contains(args.Name, "..") {
+		log.Fatalf("Invalid trigger name '%s', it must not be empty or contain '%c' or '..'\n", args.Name, filepath.Separator)
+	}
+	dir := flags.Dir
+	if len(dir) == 0 {
+		dir = config.SysDir
+	}
+	path := filepath.Join(dir, args.Name+".toml")
+	if _, err := os.Stat(path); err == nil && !flags.Force {
+		log.Fatalf("Trigger '%s' already exists, use --force to replace it\n", path)
+	}
+	// Make sure the template is valid before writing it
+	content := fmt.Sprintf(skeleton, args.Name)
+	t := triggers.Trigger{Name: args.Name, Path: path}
+	if _, err := toml.Decode(content, &t); err != nil {
+		log.Fatalf("Failed to parse template, reason: %s\n", err)
+	}
+	if err := t.Validate(); err != nil {
+		log.Fatalf("Failed to validate template, reason: %s\n", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("Failed to create '%s', reason: %s\n", dir, err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		log.Fatalf("Failed to write '%s', reason: %s\n", path, err)
+	}
+	log.Goodf("Created trigger '%s'\n", path)
+}
@@ -0,0 +1,67 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	log "github.com/DataDrake/waterlog"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// packageCommand marks the source of the package files as a command to run, rather than a file
+const packageCommand = "!"
+
+// packageFiles lists the files installed by a package, from a file with one path per line or
+// the output of a command marked by a leading "!", with "%package%" replaced by the name of the
+// package in either. Relative paths (as listed by some package managers) are taken to be relative
+// to "/".
+func packageFiles(name, source string) (files []string) {
+	var raw []byte
+	var err error
+	source = strings.ReplaceAll(source, "%package%", name)
+	if command := strings.TrimPrefix(source, packageCommand); command != source {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			log.Fatalln("The package files command is empty")
+		}
+		raw, err = exec.Command(args[0], args[1:]...).Output()
+	} else {
+		raw, err = ioutil.ReadFile(filepath.Clean(source))
+	}
+	if err != nil {
+		log.Fatalf("Failed to list the files of package '%s', reason: %s\n", name, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = "/" + line
+		}
+		files = append(files, filepath.Clean(line))
+	}
+	// An empty manifest still limits the run, rather than running everything
+	if files == nil {
+		files = []string{}
+	}
+	log.Debugf("Found %d file(s) in package '%s'\n", len(files), name)
+	return
+}
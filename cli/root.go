@@ -19,20 +19,56 @@ import (
 	log "github.com/DataDrake/waterlog"
 	"github.com/DataDrake/waterlog/format"
 	"github.com/DataDrake/waterlog/level"
+	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/triggers"
+	"github.com/getsolus/usysconf/util"
 	log2 "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // GlobalFlags contains the flags for all commands
 type GlobalFlags struct {
-	Debug  bool `short:"d" long:"debug"  desc:"Run in debug mode"`
-	Chroot bool `short:"c" long:"chroot" desc:"Specify that command is being run from a chrooted environment"`
-	Live   bool `short:"l" long:"live"   desc:"Specify that command is being run from a live medium"`
+	Debug  bool  `short:"d" long:"debug"      desc:"Run in debug mode"`
+	Chroot bool  `short:"c" long:"chroot"     desc:"Specify that command is being run from a chrooted environment"`
+	Live   bool  `short:"l" long:"live"       desc:"Specify that command is being run from a live medium"`
+	Width  int64 `short:"w" long:"task-width" desc:"Width to align task names to, longer names are truncated (default: 42)"`
+
+	Confirm        bool   `long:"confirm"                 desc:"Ask before running triggers which remove paths or run dangerous bins"`
+	NoRemove       bool   `long:"no-remove"               desc:"Never remove any paths, but still run the bins"`
+	LiveMarkers    string `long:"live-markers"            desc:"Files indicating a live medium, separated by ':' (default: /run/initramfs/livedev)"`
+	ChrootMarkers  string `long:"chroot-markers"          desc:"Files indicating a chrooted environment, separated by ':'"`
+	TriggerTimeout string `long:"trigger-timeout-default" desc:"Timeout for every bin which does not specify its own, i.e. 5m (default: none)"`
+	NoCache        bool   `long:"no-cache"                desc:"Parse every trigger from its file, ignoring the parse cache"`
+	Verbosity      int64  `long:"verbosity"               desc:"Level of detail: 1 for bin commands, 2 for bin output, 3 for debug output (default: 0)"`
+	TriggerArchive string `long:"trigger-archive"         desc:"Also load the triggers within this .tar or .tar.gz, after the system and user directories"`
+	Signatures     string `long:"signatures"              desc:"Policy for trigger files without a valid signature: off, warn or require (default: off)"`
+	TrustKey       string `long:"trust-key"               desc:"PEM encoded ed25519 public key which signs the trigger files, as <file>.sig"`
+	AssumeScope    string `long:"assume-scope"            desc:"Skip detecting the scope and assume this one instead, i.e. chroot,live,forced or none (for testing)"`
 }
 
 // Root is the main command for this application
 var Root *cmd.RootCMD
 
+// Trailing contains any arguments after a "--" separator, which are hidden from the parser
+var Trailing []string
+
 func init() {
+	// Split off trailing arguments
+	for i, arg := range os.Args {
+		if arg == "--" {
+			Trailing = os.Args[i+1:]
+			os.Args = os.Args[:i]
+			break
+		}
+	}
+	// Split off the scopes, which may be repeated, leaving the arguments of other commands alone
+	if cmd := subcommand(os.Args); cmd == Run.Name || cmd == Run.Alias {
+		Scopes, os.Args = splitScopes(os.Args)
+	}
+
 	// Build Application
 	Root = &cmd.RootCMD{
 		Name:  "usysconf",
@@ -41,8 +77,16 @@ func init() {
 	}
 	// Setup the Sub-Commands
 	Root.RegisterCMD(&cmd.Help)
+	Root.RegisterCMD(&Audit)
+	Root.RegisterCMD(&Bench)
+	Root.RegisterCMD(&Cache)
+	Root.RegisterCMD(&Config)
+	Root.RegisterCMD(&Doctor)
+	Root.RegisterCMD(&Expand)
+	Root.RegisterCMD(&New)
 	Root.RegisterCMD(&Run)
 	Root.RegisterCMD(&List)
+	Root.RegisterCMD(&State)
 	Root.RegisterCMD(&Version)
 
 	//Set up logging
@@ -50,3 +94,85 @@ func init() {
 	log.SetFormat(format.Min)
 	log.SetFlags(log2.Ltime | log2.Ldate | log2.LUTC)
 }
+
+// setMarkers overrides the files used to detect the Scope, when requested
+func setMarkers(gFlags *GlobalFlags) {
+	if len(gFlags.LiveMarkers) > 0 {
+		util.LiveMarkers = gFlags.LiveMarkers
+	}
+	if len(gFlags.ChrootMarkers) > 0 {
+		util.ChrootMarkers = gFlags.ChrootMarkers
+	}
+}
+
+// detectScope fills in the rest of a Scope from the running system, unless it is replaced by
+// --assume-scope
+func detectScope(gFlags *GlobalFlags, s triggers.Scope) triggers.Scope {
+	setMarkers(gFlags)
+	if len(gFlags.AssumeScope) == 0 {
+		return triggers.DetectScope(s)
+	}
+	for _, name := range strings.Split(gFlags.AssumeScope, ",") {
+		switch strings.TrimSpace(name) {
+		case "chroot":
+			s.Chroot = true
+		case "live":
+			s.Live = true
+		case "forced":
+			s.Forced = true
+		case "none", "":
+		default:
+			log.Fatalf("Invalid scope '%s' for --assume-scope, expected chroot, live, forced or none\n", name)
+		}
+	}
+	log.Debugf("Assuming scope: chroot=%t live=%t forced=%t\n", s.Chroot, s.Live, s.Forced)
+	return s
+}
+
+// defaultTimeout parses the timeout for bins without their own
+func defaultTimeout(gFlags *GlobalFlags) time.Duration {
+	if len(gFlags.TriggerTimeout) == 0 {
+		return 0
+	}
+	timeout, err := time.ParseDuration(gFlags.TriggerTimeout)
+	if err != nil || timeout <= 0 {
+		log.Fatalf("Invalid default trigger timeout '%s'\n", gFlags.TriggerTimeout)
+	}
+	return timeout
+}
+
+// setLoading applies the global flags which affect loading
+func setLoading(gFlags *GlobalFlags) {
+	config.NoCache = gFlags.NoCache
+	config.Archive = gFlags.TriggerArchive
+	// Only allow another key when signatures were not already being checked, since anyone who can
+	// pass flags could otherwise sign triggers with a key of their own
+	if len(gFlags.TrustKey) > 0 && filepath.Clean(gFlags.TrustKey) != filepath.Clean(config.TrustKey) {
+		switch {
+		case len(config.TrustKey) > 0:
+			log.Fatalf("The trust key '%s' chosen at build time may not be replaced\n", config.TrustKey)
+		case config.Signatures != config.SignaturesOff:
+			log.Fatalf("A trust key may not be chosen for the signature policy '%s' set at build time\n", config.Signatures)
+		}
+		config.TrustKey = gFlags.TrustKey
+	}
+	if len(gFlags.Signatures) == 0 {
+		return
+	}
+	// Only allow tightening the policy chosen at build time
+	strictness := map[string]int{"": 0, config.SignaturesOff: 0, config.SignaturesWarn: 1, config.SignaturesRequire: 2}
+	requested, ok := strictness[gFlags.Signatures]
+	if !ok {
+		log.Fatalf("Invalid signature policy '%s', must be off, warn or require\n", gFlags.Signatures)
+	}
+	if requested < strictness[config.Signatures] {
+		log.Fatalf("The signature policy '%s' may not be loosened to '%s'\n", config.Signatures, gFlags.Signatures)
+	}
+	config.Signatures = gFlags.Signatures
+}
+
+// loadAll loads every trigger, applying the global flags which affect loading
+func loadAll(gFlags *GlobalFlags) (triggers.Map, error) {
+	setLoading(gFlags)
+	return config.LoadAll()
+}
@@ -15,29 +15,89 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"github.com/DataDrake/cli-ng/cmd"
 	log "github.com/DataDrake/waterlog"
 	"github.com/DataDrake/waterlog/level"
 	"github.com/getsolus/usysconf/config"
+	"github.com/getsolus/usysconf/state"
 	"github.com/getsolus/usysconf/triggers"
 	"github.com/getsolus/usysconf/util"
 	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
 )
 
 // Run fulfills the "run" subcommand
 var Run = cmd.CMD{
 	Name:  "run",
 	Alias: "r",
-	Short: "Run specified trigger(s) to update the system configuration.",
-	Flags: &RunFlags{},
+	Short: "Run specified trigger(s) to update the system configuration, arguments after \"--\" are passed to the bins of a single trigger.",
+	Flags: &RunFlags{MaxFailures: -1, Retries: -1, Shuffle: noShuffle, Template: noTemplate},
 	Args:  &RunArgs{},
 	Run:   RunRun,
 }
 
+// ExitFailure is the exit code when any trigger failed, even when others succeeded
+const ExitFailure = 1
+
+// ExitAllSkipped is the exit code under "--strict" when every trigger was skipped
+const ExitAllSkipped = 2
+
+// ExitInterrupted is the exit code after the run was stopped by SIGINT or SIGTERM
+const ExitInterrupted = 130
+
+// ExitCheckError is the exit code under "--fail-on-check-error" when any check paths could not be resolved
+const ExitCheckError = 3
+
+// noShuffle is the default for RunFlags.Shuffle, since "--shuffle" on its own sets an empty string
+const noShuffle = "none"
+
+// noTemplate is the default for RunFlags.Template, since "--template" on its own sets an empty string
+const noTemplate = "none"
+
 // RunFlags contains the additional flags for the "run" subcommand
 type RunFlags struct {
-	Force  bool `short:"f" long:"force"   desc:"Force run the configuration regardless if it should be skipped."`
-	DryRun bool `short:"n" long:"dry-run" desc:"Test the configuration files without executing the specified binaries and arguments"`
+	Force       bool   `short:"f" long:"force"             desc:"Force run the configuration regardless if it should be skipped."`
+	DryRun      bool   `short:"n" long:"dry-run"           desc:"Test the configuration files without executing the specified binaries and arguments"`
+	Format      string `short:"o" long:"format"            desc:"Format to report the results in: text (default), json, ndjson (as each trigger finishes), junit or journal"`
+	MaxFailures int64  `short:"m" long:"max-failures"      desc:"Skip the remaining triggers once this many have failed, 0 to stop at the first (default: no limit)"`
+	Shuffle     string `short:"s" long:"shuffle"           desc:"Run the triggers in a random order, optionally with a specific seed (i.e. --shuffle=42)"`
+	Strict      bool   `short:"S" long:"strict"            desc:"Treat suspicious results as errors, i.e. exit with code 2 when every trigger was skipped, stop removing paths on the first error, warn about arguments written for a shell"`
+	Resources   bool   `short:"R" long:"resource-stats"    desc:"Print the memory and CPU time used by each bin"`
+	DumpEnv     bool   `short:"E" long:"dump-env"          desc:"Print the environment passed to each bin, with masking applied"`
+	SkipMissing bool   `short:"M" long:"skip-missing-bins" desc:"Skip triggers whose executables are not installed, instead of failing them"`
+	DoneFile    string `short:"D" long:"done-file"         desc:"Write the JSON summary to this file after a successful run, removing it otherwise"`
+	Wait        bool   `short:"W" long:"wait"              desc:"Wait for another running instance to finish, instead of exiting"`
+
+	// Scope is only here to be listed in the help, since the parser keeps the last of a repeated
+	// flag, so every "--scope" is split off into Scopes beforehand
+	Scope      string `long:"scope"                  desc:"Debug the triggers under this scope (normal, chroot or live), repeat it to compare several, i.e. --scope normal --scope chroot"`
+	Events     string `long:"events-socket"          desc:"Send a JSON event as each trigger starts and finishes to this Unix datagram socket"`
+	Profile    bool   `long:"profile"                desc:"Print the time spent loading and running the triggers"`
+	CPUProfile string `long:"cpu-profile"            desc:"Write a CPU profile (for \"go tool pprof\") to this file"`
+	Phase      string `long:"phase"                  desc:"Only run the triggers of this phase: boot or deferred (default: all)"`
+	Bundle     string `long:"bundle"                 desc:"Write the scope, configs and full output of the run to this .tar.gz for bug reports"`
+	SinceLast  bool   `long:"since-last-run"         desc:"Only run triggers whose watched paths changed since their last success"`
+	ExplainEnv bool   `long:"explain-env"            desc:"Print the environment passed to each bin with the origin of each variable, with masking applied"`
+	ForPackage string `long:"for-package"            desc:"Only run the triggers referring to the files of this package, or tagged \"always\""`
+	PkgFiles   string `long:"package-files"          desc:"File listing the files of the package for --for-package, or a command to list them after a '!', with %package% replaced by its name"`
+	CheckError bool   `long:"fail-on-check-error"    desc:"Report triggers whose check paths cannot be resolved (i.e. a bad pattern or a lack of permissions) as check-error, exiting with code 3"`
+	PerDevice  bool   `long:"concurrency-per-device" desc:"Run the invocations of a fanned-out bin in parallel across the devices holding their paths, one at a time on each device"`
+	Metrics    string `long:"metrics-file"           desc:"Write Prometheus metrics for each trigger to this file, i.e. for the node exporter textfile collector"`
+	Pick       bool   `long:"interactive"            desc:"Choose which of the triggers to run from a numbered menu, on a terminal only"`
+	Collapse   bool   `long:"collapse-failures"      desc:"Print repeated identical failures of a bin once, with their count, i.e. for large fan-outs"`
+	Retries    int64  `long:"retry-budget"           desc:"Number of retries allowed across the whole run, for every bin and trigger together (default: no limit)"`
+	Template   string `long:"template"               desc:"Print each output with this Go text/template, i.e. '{{.TriggerName}} {{.Status}}', or the default one when empty"`
+	MaxLines   int64  `long:"max-output-lines"       desc:"Only report the last lines of the output of a failed bin (default: all)"`
 }
 
 // RunArgs contains the arguments for the "run" subcommand
@@ -47,55 +107,458 @@ type RunArgs struct {
 
 // RunRun prints the usage for the requested command
 func RunRun(r *cmd.RootCMD, c *cmd.CMD) {
-	gFlags := r.Flags.(*GlobalFlags)
-	args := c.Args.(*RunArgs)
-	flags := c.Flags.(*RunFlags)
+	var cleanup cleanups
+	code := runTriggers(r.Flags.(*GlobalFlags), c.Args.(*RunArgs), c.Flags.(*RunFlags), &cleanup)
+	cleanup.run()
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// runTriggers carries out the "run" subcommand, returning the exit code
+func runTriggers(gFlags *GlobalFlags, args *RunArgs, flags *RunFlags, cleanup *cleanups) int {
 
 	// Enable Debug Output
-	if gFlags.Debug {
+	if gFlags.Debug || gFlags.Verbosity >= triggers.VerbosityDebug {
 		log.SetLevel(level.Debug)
 	}
 
+	// Set the alignment width for task names
+	if gFlags.Width > 0 {
+		triggers.TaskWidth = int(gFlags.Width)
+	}
+
+	// Machine-readable formats take over stdout, so move the logs out of the way
+	switch flags.Format {
+	case "", "text", "journal":
+	case "json", "ndjson", "junit":
+		if flags.Pick {
+			log.Fatalf("The %s format cannot be used with --interactive\n", flags.Format)
+		}
+		if flags.Template != noTemplate {
+			log.Fatalf("The %s format cannot be used with --template\n", flags.Format)
+		}
+		log.SetOutput(os.Stderr)
+	default:
+		log.Fatalf("Unsupported format '%s'\n", flags.Format)
+	}
+
+	// Fail fast on a bad output template
+	var tmpl *template.Template
+	if flags.Template != noTemplate {
+		text := flags.Template
+		if len(text) == 0 {
+			text = triggers.DefaultTemplate
+		}
+		var err error
+		if tmpl, err = triggers.ParseTemplate(text); err != nil {
+			log.Fatalf("Invalid output template, reason: %s\n", err)
+		}
+	}
+
 	log.Debugln("Started usysconf")
 	defer log.Debugln("Exiting usysconf")
 
+	// Profile the CPU usage of the whole run
+	if len(flags.CPUProfile) > 0 {
+		cleanup.add(startCPUProfile(flags.CPUProfile))
+	}
+
 	// Root user check
 	if !flags.DryRun && os.Geteuid() != 0 {
 		log.Fatalln("You must have root privileges to run triggers")
 	}
 
-	// Set Chroot as needed
-	if flags.DryRun && util.IsChroot() {
-		gFlags.Chroot = true
-	}
-
-	// Set Live as needed
-	if util.IsLive() {
-		gFlags.Live = true
+	// Prevent concurrent runs from colliding, held until every trigger of every "--scope" has run
+	if !flags.DryRun {
+		lock, err := state.Lock(flags.Wait)
+		if err != nil {
+			log.Fatalf("Failed to lock state, reason: %s\n", err)
+		}
+		cleanup.add(func() {
+			_ = state.Unlock(lock)
+		})
 	}
 
 	// Load Triggers
-	tm, err := config.LoadAll()
+	tm, err := loadAll(gFlags)
 	if err != nil {
 		log.Fatalf("Failed to load triggers, reason: %s\n", err)
 	}
 
 	// If the names flag is not present, retrieve the names of the
 	// configurations in the system and usr directories.
-	n := args.Triggers
-	if len(n) == 0 {
-		for k := range tm {
-			n = append(n, k)
+	filter := triggers.Filter{Names: args.Triggers, Phase: flags.Phase}
+	if len(flags.ForPackage) > 0 {
+		if len(flags.PkgFiles) == 0 {
+			log.Fatalln("Running for a package requires --package-files")
+		}
+		filter.Files = packageFiles(flags.ForPackage, flags.PkgFiles)
+	}
+	// Let the operator narrow down the triggers
+	if flags.Pick {
+		names, err := filter.Select(tm)
+		if err != nil {
+			log.Fatalf("Failed to select triggers, reason: %s\n", err)
+		}
+		if filter.Names = pickTriggers(tm, names); len(filter.Names) == 0 {
+			log.Infoln("No triggers chosen")
+			return 0
+		}
+	}
+	// Randomize the order of the triggers
+	if flags.Shuffle != noShuffle {
+		filter.Shuffle = true
+		filter.Seed = time.Now().UnixNano()
+		if len(flags.Shuffle) > 0 {
+			if filter.Seed, err = strconv.ParseInt(flags.Shuffle, 10, 64); err != nil {
+				log.Fatalf("Invalid shuffle seed '%s'\n", flags.Shuffle)
+			}
+		}
+		log.Infof("Shuffling triggers with seed: %d\n", filter.Seed)
+	}
+	// Pass trailing arguments on to a single trigger
+	if len(Trailing) > 0 {
+		if len(args.Triggers) != 1 {
+			log.Fatalln("Trailing arguments may only be used when running a single trigger")
+		}
+		if t, ok := tm[args.Triggers[0]]; ok {
+			t.AppendArgs(Trailing)
+			tm[args.Triggers[0]] = t
 		}
 	}
 	// Establish scope of operations
-	s := triggers.Scope{
+	s := detectScope(gFlags, triggers.Scope{
 		Chroot: gFlags.Chroot,
 		Debug:  gFlags.Debug,
 		DryRun: flags.DryRun,
 		Forced: flags.Force,
 		Live:   gFlags.Live,
+
+		Verbosity: int(gFlags.Verbosity),
+
+		Confirm:     gFlags.Confirm,
+		NoRemove:    gFlags.NoRemove,
+		MaxFailures: int(flags.MaxFailures),
+		FailFast:    flags.MaxFailures == 0,
+		Strict:      flags.Strict,
+
+		FailOnCheckError: flags.CheckError,
+		CollapseFailures: flags.Collapse,
+		MaxOutputLines:   int(flags.MaxLines),
+
+		DumpEnv:       flags.DumpEnv,
+		ExplainEnv:    flags.ExplainEnv,
+		ResourceStats: flags.Resources,
+		PerDevice:     flags.PerDevice,
+
+		SkipMissingBins: flags.SkipMissing,
+		SinceLastRun:    flags.SinceLast,
+		DefaultTimeout:  defaultTimeout(gFlags),
+	})
+	// Stream progress to a monitor, if one is listening
+	if len(flags.Events) > 0 {
+		if s.Events, err = triggers.DialEvents(flags.Events); err != nil {
+			log.Warnf("Failed to connect to events socket '%s', reason: %s\n", flags.Events, err)
+		}
+		cleanup.add(func() {
+			_ = s.Events.Close()
+		})
+	}
+	s.Template = tmpl
+	// Bound the retries of the whole run
+	if flags.Retries >= 0 {
+		s.RetryBudget = triggers.NewRetryBudget(int(flags.Retries))
 	}
+	// Stream each result as soon as it is known
+	if flags.Format == "ndjson" {
+		s.Stream = triggers.NewStream(os.Stdout)
+	}
+	// Compare triggers across scopes
+	if len(Scopes) > 0 {
+		names, err := filter.Select(tm)
+		if err != nil {
+			log.Fatalf("Failed to select triggers, reason: %s\n", err)
+		}
+		runScopes(tm, s, names, Scopes)
+		return 0
+	}
+	// Stop cleanly on the first interrupt, and immediately on the second
+	ctx, stop := interruptible(cleanup)
+	defer stop()
+	s.Context = ctx
 	// Run triggers
-	triggers.Run(tm, s, n)
+	start := time.Now()
+	ran, err := triggers.RunAll(tm, s, filter)
+	if err != nil {
+		log.Fatalf("Failed to select triggers, reason: %s\n", err)
+	}
+	execution := time.Since(start)
+	results := triggers.Triggers(ran)
+	summary := triggers.Summarize(results)
+	if flags.Profile {
+		printProfile(execution)
+	}
+	// Report results
+	switch flags.Format {
+	case "json":
+		if err := summary.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("Failed to write JSON summary, reason: %s\n", err)
+		}
+	case "junit":
+		if err := triggers.WriteJUnit(os.Stdout, results); err != nil {
+			log.Fatalf("Failed to write JUnit report, reason: %s\n", err)
+		}
+	case "journal":
+		if err := triggers.WriteJournal(os.Stderr, results); err != nil {
+			log.Errorf("Failed to write to the journal, reason: %s\n", err)
+		}
+	}
+	// Collect everything needed for a bug report
+	if len(flags.Bundle) > 0 {
+		writeBundle(flags.Bundle, s, results, summary)
+	}
+	// Expose the results to monitoring
+	if len(flags.Metrics) > 0 {
+		writeMetrics(flags.Metrics, results)
+	}
+	// Report on what completed before an interrupt
+	if ctx.Err() != nil {
+		log.Warnf("Interrupted: %d succeeded, %d failed, %d partial, %d skipped\n",
+			summary.Succeeded, summary.Failed, summary.Partial, summary.Skipped)
+		return ExitInterrupted
+	}
+	// Mark the run as done for anything waiting on it
+	if len(flags.DoneFile) > 0 {
+		writeDone(flags.DoneFile, summary)
+	}
+	// Exit distinctly for unresolvable check paths, which may mean the system is broken
+	if summary.CheckErrors > 0 {
+		log.Errorf("The check paths of %d trigger(s) could not be resolved\n", summary.CheckErrors)
+		return ExitCheckError
+	}
+	// Any failure fails the run, including a Partial trigger
+	if summary.Failed+summary.Partial > 0 {
+		return ExitFailure
+	}
+	// Warn about runs which did nothing at all
+	executed := 0
+	for _, t := range results {
+		if t.Status() != triggers.Skipped {
+			executed++
+		}
+	}
+	if len(results) > 0 && executed == 0 {
+		log.Warnf("All %d trigger(s) were skipped, check the scope and skip paths if this was unexpected\n", len(results))
+		if flags.Strict {
+			return ExitAllSkipped
+		}
+	}
+	return 0
+}
+
+// pickTriggers asks which of the named triggers to run, listing them in sorted order so that the
+// numbering is the same on every run
+func pickTriggers(tm triggers.Map, names []string) []string {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	options := make([]string, len(names))
+	for i, name := range names {
+		options[i] = name
+		if desc := tm[name].Description; len(desc) > 0 {
+			options[i] += " - " + desc
+		}
+	}
+	indices, err := util.Pick("Triggers to run", options)
+	if err != nil {
+		log.Fatalf("Failed to choose triggers, reason: %s\n", err)
+	}
+	picked := make([]string, len(indices))
+	for i, index := range indices {
+		picked[i] = names[index]
+	}
+	return picked
+}
+
+// cleanups are the steps to finish a run, which are also taken when it is forced to exit
+type cleanups struct {
+	once  sync.Once
+	steps []func()
+}
+
+// add appends a step to the cleanups
+func (c *cleanups) add(step func()) {
+	c.steps = append(c.steps, step)
+}
+
+// run takes every step once, in the reverse order they were added
+func (c *cleanups) run() {
+	c.once.Do(func() {
+		for i := len(c.steps) - 1; i >= 0; i-- {
+			c.steps[i]()
+		}
+	})
+}
+
+// interruptible creates a Context which is cancelled by SIGINT or SIGTERM, exiting immediately on
+// the second signal, after the cleanups. The returned function stops handling the signals.
+func interruptible(cleanup *cleanups) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		log.Warnf("Received %s, stopping the current bin and skipping the rest, send it again to exit immediately\n", sig)
+		cancel()
+		if _, ok = <-signals; ok {
+			cleanup.run()
+			os.Exit(ExitInterrupted)
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(signals)
+		cancel()
+	}
+}
+
+// writeBundle saves a bundle for bug reports to path
+func writeBundle(path string, s triggers.Scope, results []triggers.Trigger, summary triggers.Summary) {
+	var buff bytes.Buffer
+	if err := triggers.WriteBundle(&buff, s, results, summary); err != nil {
+		log.Errorf("Failed to generate bundle, reason: %s\n", err)
+		return
+	}
+	if err := util.WriteFileAtomic(path, buff.Bytes(), 0600); err != nil {
+		log.Errorf("Failed to write bundle '%s', reason: %s\n", path, err)
+		return
+	}
+	log.Infof("Wrote bundle to '%s'\n", path)
+}
+
+// writeMetrics saves the metrics of a run to path
+func writeMetrics(path string, results []triggers.Trigger) {
+	var buff bytes.Buffer
+	if err := triggers.WriteMetrics(&buff, results); err != nil {
+		log.Errorf("Failed to generate metrics, reason: %s\n", err)
+		return
+	}
+	if err := util.WriteFileAtomic(path, buff.Bytes(), 0644); err != nil {
+		log.Errorf("Failed to write metrics file '%s', reason: %s\n", path, err)
+	}
+}
+
+// writeDone writes out the summary of a successful run, or removes the done file after a failure
+func writeDone(path string, summary triggers.Summary) {
+	if summary.Failed > 0 || summary.Partial > 0 || summary.CheckErrors > 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to remove done file '%s', reason: %s\n", path, err)
+		}
+		return
+	}
+	var buff bytes.Buffer
+	if err := summary.WriteJSON(&buff); err != nil {
+		log.Errorf("Failed to generate done file, reason: %s\n", err)
+		return
+	}
+	if err := util.WriteFileAtomic(path, buff.Bytes(), 0644); err != nil {
+		log.Errorf("Failed to write done file '%s', reason: %s\n", path, err)
+	}
+}
+
+// Scopes contains the value of every "--scope" flag, which are hidden from the parser
+var Scopes []string
+
+// subcommand finds the name of the command being run, the first argument which is not a flag, since
+// every flag is written as "--flag=value"
+func subcommand(args []string) string {
+	for _, arg := range args[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// splitScopes removes every "--scope <name>" and "--scope=<name>" from the arguments, returning
+// the names in order
+func splitScopes(args []string) (scopes, rest []string) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case strings.HasPrefix(arg, "--scope="):
+			scopes = append(scopes, strings.TrimPrefix(arg, "--scope="))
+		case arg == "--scope" && i+1 < len(args):
+			scopes = append(scopes, args[i+1])
+			i++
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return
+}
+
+// runScopes runs the triggers once for each of the named scopes, under a heading for each. Every
+// scope starts from the same state, and the state is not saved afterwards.
+func runScopes(tm triggers.Map, base triggers.Scope, names, scopes []string) {
+	prev := state.Load()
+	for _, name := range scopes {
+		s := base
+		switch name {
+		case "normal":
+			s.Chroot, s.Live = false, false
+		case "chroot":
+			s.Chroot, s.Live = true, false
+		case "live":
+			s.Chroot, s.Live = false, true
+		default:
+			log.Fatalf("Unsupported scope '%s'\n", name)
+		}
+		log.Infof("Running under the '%s' scope:\n", name)
+		triggers.RunWithState(tm, s, names, prev, make(state.Map))
+	}
+}
+
+// startCPUProfile begins writing a CPU profile to path, returning a function to finish it
+func startCPUProfile(path string) func() {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create CPU profile '%s', reason: %s\n", path, err)
+	}
+	if err = pprof.StartCPUProfile(f); err != nil {
+		log.Fatalf("Failed to start CPU profile, reason: %s\n", err)
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			log.Errorf("Failed to write CPU profile '%s', reason: %s\n", path, err)
+		}
+	}
+}
+
+// printProfile prints a breakdown of the time spent in each stage of a run
+func printProfile(execution time.Duration) {
+	stages := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"Discovery", config.Timing.Discovery},
+		{"Parsing", config.Timing.Parsing},
+		{"Validation", config.Timing.Validation},
+		{"Execution", execution},
+	}
+	var total time.Duration
+	for _, stage := range stages {
+		total += stage.duration
+	}
+	log.Printf("\n%-12s %12s %8s\n", "Stage", "Time", "Share")
+	for _, stage := range stages {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(stage.duration) / float64(total)
+		}
+		log.Printf("%-12s %12s %7.1f%%\n", stage.name, stage.duration.Round(time.Microsecond), share)
+	}
 }
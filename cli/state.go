@@ -0,0 +1,92 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"github.com/DataDrake/cli-ng/cmd"
+	log "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/util"
+	"os"
+	"path/filepath"
+)
+
+// State fulfills the "state" subcommand
+var State = cmd.CMD{
+	Name:  "state",
+	Alias: "st",
+	Short: "Save or restore the state of previous runs, i.e. \"state export <path>\" or \"state import <path>\"",
+	Args:  &StateArgs{},
+	Run:   StateRun,
+}
+
+// StateArgs contains the arguments for the "state" subcommand
+type StateArgs struct {
+	Action string `desc:"Action to perform on the state (export, import)"`
+	Path   string `desc:"Snapshot to write to or read from"`
+}
+
+// StateRun exports the state to a snapshot, or replaces it with one
+func StateRun(r *cmd.RootCMD, c *cmd.CMD) {
+	// gFlags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*StateArgs)
+	switch args.Action {
+	case "export":
+		// Do not read the state while a running instance is replacing it
+		lock, err := state.Lock(false)
+		if err != nil {
+			log.Fatalf("Failed to lock state, reason: %s\n", err)
+		}
+		defer state.Unlock(lock)
+		m, err := state.Read()
+		if err != nil {
+			log.Fatalf("Failed to read the state, reason: %s\n", err)
+		}
+		var buff bytes.Buffer
+		if err := m.Export(&buff); err != nil {
+			log.Fatalf("Failed to export the state, reason: %s\n", err)
+		}
+		if err := util.WriteFileAtomic(args.Path, buff.Bytes(), 0600); err != nil {
+			log.Fatalf("Failed to write snapshot '%s', reason: %s\n", args.Path, err)
+		}
+		log.Goodf("Exported %d entries to '%s'\n", len(m), args.Path)
+	case "import":
+		if os.Geteuid() != 0 {
+			log.Fatalln("You must have root privileges to import the state")
+		}
+		f, err := os.Open(filepath.Clean(args.Path))
+		if err != nil {
+			log.Fatalf("Failed to open snapshot '%s', reason: %s\n", args.Path, err)
+		}
+		m, err := state.Import(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatalf("Failed to read snapshot '%s', reason: %s\n", args.Path, err)
+		}
+		// Do not replace the state under a running instance
+		lock, err := state.Lock(false)
+		if err != nil {
+			log.Fatalf("Failed to lock state, reason: %s\n", err)
+		}
+		defer state.Unlock(lock)
+		if err = m.Save(); err != nil {
+			log.Fatalf("Failed to save the state, reason: %s\n", err)
+		}
+		log.Goodf("Imported %d entries from '%s'\n", len(m), args.Path)
+	default:
+		log.Fatalf("Unsupported state action '%s'\n", args.Action)
+	}
+}
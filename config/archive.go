@@ -0,0 +1,92 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	wlog "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/triggers"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive is a .tar or .tar.gz of additional triggers for LoadAll, none when empty
+var Archive string
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// LoadArchive reads in all of the trigger files within a .tar or .tar.gz, returning an error for
+// every entry which could not be loaded. Entries in directories are named after the file alone.
+func LoadArchive(archive string) (tm triggers.Map, failures []error) {
+	tm = make(triggers.Map)
+	// Read the whole archive at once, so that exactly what was verified is used
+	raw, err := readSigned(filepath.Clean(archive))
+	if err != nil {
+		failures = append(failures, fmt.Errorf("failed to open archive '%s', reason: %s", archive, err))
+		return
+	}
+	var r io.Reader = bufio.NewReader(bytes.NewReader(raw))
+	if magic, _ := r.(*bufio.Reader).Peek(len(gzipMagic)); string(magic) == string(gzipMagic) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to decompress archive '%s', reason: %s", archive, err))
+			return
+		}
+		defer gz.Close()
+		r = gz
+	}
+	wlog.Debugf("Scanning archive '%s':\n", archive)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to read archive '%s', reason: %s", archive, err))
+			return
+		}
+		name := path.Base(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(name, ".toml") {
+			continue
+		}
+		t := triggers.Trigger{
+			Name:    strings.TrimSuffix(name, ".toml"),
+			Path:    archive + ":" + hdr.Name,
+			Archive: archive,
+		}
+		wlog.Debugf("    Found '%s'\n", t.Name)
+		cfg, err := ioutil.ReadAll(tr)
+		if err == nil {
+			if err = t.Parse(cfg, t.Path); err == nil {
+				err = t.Validate()
+			}
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to read '%s' from '%s' reason: %s", hdr.Name, archive, err.Error()))
+			continue
+		}
+		tm[t.Name] = t
+	}
+	return
+}
@@ -0,0 +1,183 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	wlog "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/triggers"
+	"github.com/getsolus/usysconf/util"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// NoCache disables the parse cache, so every trigger is read from its file
+var NoCache bool
+
+// cacheEntry is a parsed trigger, along with the details of the file it was parsed from
+type cacheEntry struct {
+	ModTime time.Time
+	Size    int64
+	Trigger triggers.Trigger
+}
+
+// parseCache holds the parsed triggers by path, to avoid decoding unchanged files again
+type parseCache struct {
+	Entries map[string]cacheEntry
+	dirty   bool
+}
+
+// cache is loaded from CachePath on first use
+var cache *parseCache
+
+// cacheFormat is the layout of the parse cache, to be increased whenever it changes
+const cacheFormat = 1
+
+// cacheVersion is written ahead of the parse cache, so that a cache written by another build of
+// usysconf is thrown away rather than decoded into triggers with other fields. It covers both the
+// layout of the cache and the fields of a Trigger.
+var cacheVersion = fmt.Sprintf("%d-%x", cacheFormat, typeHash(reflect.TypeOf(triggers.Trigger{})))
+
+// typeHash fingerprints the exported fields of a type, and of every type within it, which are the
+// ones kept in the cache
+func typeHash(t reflect.Type) []byte {
+	h := sha256.New()
+	describeType(h, t, make(map[reflect.Type]bool))
+	return h.Sum(nil)[:8]
+}
+
+// describeType writes out the name, tags and type of every exported field of a type, recursively
+func describeType(w io.Writer, t reflect.Type, seen map[reflect.Type]bool) {
+	fmt.Fprintf(w, "%s;", t)
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		describeType(w, t.Elem(), seen)
+	case reflect.Map:
+		describeType(w, t.Key(), seen)
+		describeType(w, t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s %q ", f.Name, f.Tag)
+			describeType(w, f.Type, seen)
+		}
+	}
+}
+
+// loadCache reads in the parse cache, starting over when it is missing or unreadable
+func loadCache() *parseCache {
+	if cache != nil {
+		return cache
+	}
+	cache = &parseCache{Entries: make(map[string]cacheEntry)}
+	if NoCache || len(CachePath) == 0 {
+		return cache
+	}
+	raw, err := ioutil.ReadFile(filepath.Clean(CachePath))
+	if err != nil {
+		return cache
+	}
+	dec := gob.NewDecoder(bytes.NewReader(raw))
+	var version string
+	if err = dec.Decode(&version); err == nil && version != cacheVersion {
+		err = fmt.Errorf("written by another version of usysconf")
+	}
+	if err == nil {
+		err = dec.Decode(cache)
+	}
+	if err != nil {
+		wlog.Debugf("Ignoring parse cache '%s', reason: %s\n", CachePath, err)
+		cache.Entries = make(map[string]cacheEntry)
+	}
+	return cache
+}
+
+// lookup gets a parsed trigger, if the file has not changed since it was cached
+func (c *parseCache) lookup(path string, info os.FileInfo) (t triggers.Trigger, ok bool) {
+	if NoCache {
+		return
+	}
+	entry, ok := c.Entries[path]
+	if !ok || !entry.ModTime.Equal(info.ModTime()) || entry.Size != info.Size() {
+		return t, false
+	}
+	return entry.Trigger, true
+}
+
+// store saves a parsed trigger, before it has been validated
+func (c *parseCache) store(path string, info os.FileInfo, t triggers.Trigger) {
+	if NoCache {
+		return
+	}
+	c.Entries[path] = cacheEntry{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+		Trigger: t,
+	}
+	c.dirty = true
+}
+
+// saveCache writes out the parse cache, if anything was added to it. Failures only slow down the
+// next run, so they are not reported as errors.
+func saveCache() {
+	if cache == nil || !cache.dirty || NoCache || len(CachePath) == 0 {
+		return
+	}
+	var buff bytes.Buffer
+	enc := gob.NewEncoder(&buff)
+	if err := enc.Encode(cacheVersion); err != nil {
+		wlog.Debugf("Failed to encode parse cache, reason: %s\n", err)
+		return
+	}
+	if err := enc.Encode(cache); err != nil {
+		wlog.Debugf("Failed to encode parse cache, reason: %s\n", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(CachePath), 0750); err != nil {
+		wlog.Debugf("Failed to create directory for parse cache, reason: %s\n", err)
+		return
+	}
+	if err := util.WriteFileAtomic(CachePath, buff.Bytes(), 0640); err != nil {
+		wlog.Debugf("Failed to write parse cache '%s', reason: %s\n", CachePath, err)
+		return
+	}
+	cache.dirty = false
+}
+
+// ClearCache removes the parse cache
+func ClearCache() error {
+	cache = nil
+	if len(CachePath) == 0 {
+		return nil
+	}
+	if err := os.Remove(CachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
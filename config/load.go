@@ -23,23 +23,48 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// Timings records the time spent loading triggers, across all of the calls to LoadEach
+type Timings struct {
+	Discovery  time.Duration
+	Parsing    time.Duration
+	Validation time.Duration
+}
+
+// Timing is the time spent loading triggers so far
+var Timing Timings
+
 // Load reads in all of the trigger files in a directory
 func Load(path string) (tm triggers.Map, err error) {
+	tm, failures := LoadEach(path)
+	if len(failures) > 0 {
+		err = failures[0]
+	}
+	return
+}
+
+// LoadEach reads in all of the trigger files in a directory, returning an error for every file
+// which could not be loaded instead of stopping at the first. The path may instead be a single
+// file of triggers, see LoadMerged.
+func LoadEach(path string) (tm triggers.Map, failures []error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		return LoadMerged(path)
+	}
 	tm = make(triggers.Map)
+	start := time.Now()
 	entries, err := ioutil.ReadDir(path)
+	Timing.Discovery += time.Since(start)
 	if err != nil {
 		wlog.Debugf("Skipped directory '%s':\n", path)
 	}
 	if os.IsNotExist(err) {
 		wlog.Debugf("    Not found.\n")
-		err = nil
 		return
 	}
 	if err != nil {
 		wlog.Debugf("    Failed to read triggers, reason: %s\n", err)
-		err = nil
 		return
 	}
 	wlog.Debugf("Scanning directory '%s':\n", path)
@@ -59,13 +84,29 @@ func Load(path string) (tm triggers.Map, err error) {
 		// found trigger
 		wlog.Debugf("    Found '%s'\n", t.Name)
 		found = true
-		if err = t.Load(t.Path); err == nil {
+		start = time.Now()
+		if verifying() {
+			// The cache cannot be trusted, so parse exactly what was verified
+			var cfg []byte
+			if cfg, err = readSigned(t.Path); err == nil {
+				err = t.Parse(cfg, t.Path)
+			}
+		} else if cached, ok := loadCache().lookup(t.Path, entry); ok {
+			cached.Name, cached.Path = t.Name, t.Path
+			t = cached
+		} else if err = t.Load(t.Path); err == nil {
+			loadCache().store(t.Path, entry, t)
+		}
+		Timing.Parsing += time.Since(start)
+		if err == nil {
+			start = time.Now()
 			// Check the config for problems
 			err = t.Validate()
+			Timing.Validation += time.Since(start)
 		}
 		if err != nil {
-			err = fmt.Errorf("failed to read '%s' from '%s' reason: %s", name, path, err.Error())
-			return
+			failures = append(failures, fmt.Errorf("failed to read '%s' from '%s' reason: %s", name, path, err.Error()))
+			continue
 		}
 		// save trigger
 		tm[t.Name] = t
@@ -76,9 +117,39 @@ func Load(path string) (tm triggers.Map, err error) {
 	return
 }
 
-// LoadAll will check the system, user, and home directories, in that order, for a
-// configuration file that has the passed name parameter, without the extension
-// and will create a config with the specified valus.
+// HomeDir finds the home directory of the user running usysconf, looking through sudo. It is
+// empty when running as root without sudo, since root has no home triggers.
+func HomeDir() (home string, err error) {
+	if home, err = os.UserHomeDir(); err != nil {
+		return
+	}
+	// replace the root directory with the user home directory executing usysconf
+	if os.Getuid() == 0 {
+		username := os.Getenv("SUDO_USER")
+		if username == "" || username == "root" {
+			// if user is not found or it is actually being run by root without sudo return
+			home = ""
+			return
+		}
+		// Lookup sudo user's home directory
+		u, err := user.Lookup(username)
+		if err != nil {
+			wlog.Warnf("Failed to lookup user '%s', reason: %s\n", username, err)
+		} else {
+			home = u.HomeDir
+		}
+	}
+	return home, nil
+}
+
+// HomeTriggers gets the directory of triggers within a home directory
+func HomeTriggers(home string) string {
+	return filepath.Join(home, ".config", "usysconf.d")
+}
+
+// LoadAll will check the system and user directories, the Archive, and the home
+// directory, in that order, for a configuration file that has the passed name
+// parameter, without the extension and will create a config with the specified valus.
 func LoadAll() (tm triggers.Map, err error) {
 	// Read from System directory
 	tm, err = Load(SysDir)
@@ -92,37 +163,41 @@ func LoadAll() (tm triggers.Map, err error) {
 	}
 	triggers.Merge(tm, tm2)
 
+	// Read from the Archive, if any
+	if len(Archive) > 0 {
+		var failures []error
+		if tm2, failures = LoadArchive(Archive); len(failures) > 0 {
+			err = failures[0]
+			return
+		}
+		triggers.Merge(tm, tm2)
+	}
+
 	// Read from Home directory
-	home, err := os.UserHomeDir()
+	home, err := HomeDir()
 	if err != nil {
 		return
 	}
-
-	// replace the root directory with the user home directory executing usysconf
-	if os.Getuid() == 0 {
-		username := os.Getenv("SUDO_USER")
-		if username == "" || username == "root" {
-			// if user is not found or it is actually being run by root without sudo return
-			wlog.Warnln("Home Triggers not loaded")
-			goto CHECK
-		}
-		// Lookup sudo user's home directory
-		u, err := user.Lookup(username)
-		if err != nil {
-			wlog.Warnf("Failed to lookup user '%s', reason: %s\n", username, err)
-		} else {
-			home = u.HomeDir
-		}
+	if len(home) == 0 {
+		wlog.Warnln("Home Triggers not loaded")
+		goto CHECK
 	}
 
 	// Load configs from the user's Home directory
-	tm2, err = Load(filepath.Join(home, ".config", "usysconf.d"))
+	tm2, err = Load(HomeTriggers(home))
 	if err != nil {
 		return
 	}
 	triggers.Merge(tm, tm2)
 
 CHECK:
+	saveCache()
+	// Add the triggers registered by an embedder, unless replaced by a file
+	for name, t := range triggers.Registered() {
+		if _, ok := tm[name]; !ok {
+			tm[name] = t
+		}
+	}
 	// check for lack of triggers
 	if len(tm) == 0 {
 		wlog.Fatalln("No triggers available")
@@ -0,0 +1,91 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	wlog "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/triggers"
+	"path/filepath"
+	"time"
+)
+
+// mergedConfig is a single file containing many triggers, as "[[trigger]]" tables
+type mergedConfig struct {
+	Triggers []toml.Primitive `toml:"trigger"`
+}
+
+// mergedName is the name of a trigger within a mergedConfig, which usually comes from its file
+type mergedName struct {
+	Name string `toml:"name"`
+}
+
+// LoadMerged reads in all of the triggers in a single file, where each "[[trigger]]" table is a
+// trigger with a "name". Returns an error for every trigger which could not be loaded, including
+// every trigger after the first with the same name.
+func LoadMerged(path string) (tm triggers.Map, failures []error) {
+	tm = make(triggers.Map)
+	path = filepath.Clean(path)
+	raw, err := readSigned(path)
+	if err != nil {
+		failures = append(failures, fmt.Errorf("failed to read '%s', reason: %s", path, err))
+		return
+	}
+	var merged mergedConfig
+	start := time.Now()
+	md, err := toml.Decode(string(raw), &merged)
+	Timing.Parsing += time.Since(start)
+	if err != nil {
+		failures = append(failures, fmt.Errorf("failed to read '%s', reason: %s", path, err))
+		return
+	}
+	wlog.Debugf("Scanning file '%s':\n", path)
+	seen := make(map[string]int)
+	for i, prim := range merged.Triggers {
+		var name mergedName
+		if err = md.PrimitiveDecode(prim, &name); err == nil && len(name.Name) == 0 {
+			err = fmt.Errorf("missing a name")
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to read trigger %d from '%s' reason: %s", i+1, path, err))
+			continue
+		}
+		if first, ok := seen[name.Name]; ok {
+			failures = append(failures, fmt.Errorf("trigger %d from '%s' has the same name '%s' as trigger %d", i+1, path, name.Name, first))
+			continue
+		}
+		seen[name.Name] = i + 1
+		t := triggers.Trigger{
+			Name: name.Name,
+			Path: path,
+		}
+		wlog.Debugf("    Found '%s'\n", t.Name)
+		start = time.Now()
+		err = md.PrimitiveDecode(prim, &t)
+		Timing.Parsing += time.Since(start)
+		if err == nil {
+			start = time.Now()
+			err = t.Validate()
+			Timing.Validation += time.Since(start)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to read '%s' from '%s' reason: %s", t.Name, path, err))
+			continue
+		}
+		tm[t.Name] = t
+	}
+	return
+}
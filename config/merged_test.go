@@ -0,0 +1,64 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const duplicated = `
+[[trigger]]
+name = "dup"
+description = "first"
+
+[[trigger.bins]]
+bin = "/bin/true"
+
+[[trigger]]
+name = "other"
+
+[[trigger.bins]]
+bin = "/bin/true"
+
+[[trigger]]
+name = "dup"
+description = "second"
+
+[[trigger.bins]]
+bin = "/bin/true"
+`
+
+func TestLoadMergedDuplicate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-merged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "triggers.toml")
+	if err := ioutil.WriteFile(path, []byte(duplicated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm, failures := LoadMerged(path)
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "trigger 3") || !strings.Contains(failures[0].Error(), "trigger 1") {
+		t.Fatalf("expected a failure naming both triggers, got %v", failures)
+	}
+	if len(tm) != 2 || tm["dup"].Description != "first" {
+		t.Errorf("expected the first 'dup' to be kept, got %v", tm)
+	}
+}
@@ -19,4 +19,6 @@ var (
 	UsrDir string
 	// SysDir is the path defined during build (Makefile) i.e. /etc/usysconf.d
 	SysDir string
+	// CachePath is the path defined during build (Makefile) i.e. /var/cache/usysconf/triggers
+	CachePath string
 )
@@ -0,0 +1,133 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	wlog "github.com/DataDrake/waterlog"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+const (
+	// SignaturesOff loads trigger files without checking for signatures
+	SignaturesOff = "off"
+	// SignaturesWarn loads unsigned or badly signed trigger files, with a warning
+	SignaturesWarn = "warn"
+	// SignaturesRequire refuses to load unsigned or badly signed trigger files
+	SignaturesRequire = "require"
+)
+
+// Signatures is the policy for trigger files without a valid signature, may be set at build time
+var Signatures = SignaturesOff
+
+// TrustKey is the PEM encoded ed25519 public key which signs the trigger files, may be set at build
+// time
+var TrustKey string
+
+// SignatureSuffix is added to the path of a trigger file (or archive) to find its detached signature
+const SignatureSuffix = ".sig"
+
+var (
+	trustKey     ed25519.PublicKey
+	trustKeyErr  error
+	trustKeyOnce sync.Once
+)
+
+// loadTrustKey reads the TrustKey, which is only done once
+func loadTrustKey() {
+	if len(TrustKey) == 0 {
+		trustKeyErr = fmt.Errorf("no public key to verify signatures with")
+		return
+	}
+	raw, err := ioutil.ReadFile(TrustKey)
+	if err != nil {
+		trustKeyErr = fmt.Errorf("failed to read public key, reason: %s", err)
+		return
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		trustKeyErr = fmt.Errorf("public key '%s' is not PEM encoded", TrustKey)
+		return
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		trustKeyErr = fmt.Errorf("failed to parse public key '%s', reason: %s", TrustKey, err)
+		return
+	}
+	var ok bool
+	if trustKey, ok = key.(ed25519.PublicKey); !ok {
+		trustKeyErr = fmt.Errorf("public key '%s' is not an ed25519 key", TrustKey)
+	}
+}
+
+// verifySignature checks the contents of a file against its detached signature, which is either
+// the raw 64 bytes or their base64 encoding
+func verifySignature(path string, data []byte) error {
+	trustKeyOnce.Do(loadTrustKey)
+	if trustKeyErr != nil {
+		return trustKeyErr
+	}
+	sig, err := ioutil.ReadFile(path + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to read signature, reason: %s", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		if sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+			return fmt.Errorf("malformed signature '%s%s'", path, SignatureSuffix)
+		}
+	}
+	if !ed25519.Verify(trustKey, data, sig) {
+		return fmt.Errorf("invalid signature '%s%s'", path, SignatureSuffix)
+	}
+	return nil
+}
+
+// checkSignature applies the Signatures policy to a file, returning an error when it must not be
+// loaded
+func checkSignature(path string, data []byte) error {
+	switch Signatures {
+	case SignaturesOff, "":
+		return nil
+	case SignaturesWarn:
+		if err := verifySignature(path, data); err != nil {
+			wlog.Warnf("Loading '%s' regardless, reason: %s\n", path, err)
+		}
+		return nil
+	case SignaturesRequire:
+		return verifySignature(path, data)
+	}
+	return fmt.Errorf("invalid signature policy '%s'", Signatures)
+}
+
+// readSigned reads a file, applying the Signatures policy to its contents
+func readSigned(path string) (data []byte, err error) {
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	err = checkSignature(path, data)
+	return
+}
+
+// verifying checks if the signatures of trigger files are checked, which requires reading every file
+// rather than using the parse cache
+func verifying() bool {
+	return Signatures != SignaturesOff && len(Signatures) > 0
+}
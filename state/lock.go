@@ -0,0 +1,55 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ErrLocked indicates that another process is holding the lock
+var ErrLocked = errors.New("another instance of usysconf is already running")
+
+// Lock takes an exclusive lock on the state directory, optionally waiting for it to be released
+// by another process. The lock is released by Unlock, or by the kernel whenever this process
+// exits (including due to a signal).
+func Lock(wait bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(Path), 0750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(Path), "lock"), os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	if err = syscall.Flock(int(f.Fd()), how); err != nil {
+		_ = f.Close()
+		if err == syscall.EWOULDBLOCK {
+			err = ErrLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Unlock releases a lock taken by Lock
+func Unlock(f *os.File) error {
+	return f.Close()
+}
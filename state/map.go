@@ -31,17 +31,23 @@ var Path string
 // Map contains a list files and their modification times
 type Map map[string]time.Time
 
-// Load reads in the state if it exists and deserializes it
+// Load reads in the state if it exists and deserializes it, or an empty Map if it cannot be read
 func Load() Map {
+	m, _ := Read()
+	return m
+}
+
+// Read deserializes the state like Load, but returns an error when it is missing or cannot be decoded
+func Read() (Map, error) {
 	m := make(Map)
 	sFile, err := os.Open(filepath.Clean(Path))
 	if err != nil {
-		return m
+		return m, err
 	}
 	dec := cbor.NewDecoder(sFile)
-	_ = dec.Decode(m)
+	err = dec.Decode(&m)
 	_ = sFile.Close()
-	return m
+	return m, err
 }
 
 // Save writes out the current state for future runs
@@ -53,8 +59,13 @@ func (m Map) Save() error {
 	if err != nil {
 		return err
 	}
-	enc := cbor.NewEncoder(sFile)
-	err = enc.Encode(m)
+	// Keep the full precision of the modification times, so that they compare equal on the next run
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		_ = sFile.Close()
+		return err
+	}
+	err = mode.NewEncoder(sFile).Encode(m)
 	_ = sFile.Close()
 	return err
 }
@@ -109,9 +120,8 @@ func (m Map) Search(paths []string) Map {
 	return match
 }
 
-// Exclude removes keys from the Map if they match certain patterns
+// Exclude gets a copy of the Map without the keys matching certain patterns, leaving the Map itself alone
 func (m Map) Exclude(patterns []string) Map {
-	match := make(Map)
 	var regexes []*regexp.Regexp
 	for _, pattern := range patterns {
 		exclude := pattern
@@ -123,13 +133,18 @@ func (m Map) Exclude(patterns []string) Map {
 		}
 		regexes = append(regexes, regex)
 	}
-	for k := range m {
+	match := make(Map)
+	for k, v := range m {
+		excluded := false
 		for _, regex := range regexes {
 			if regex.MatchString(k) {
-				delete(m, k)
+				excluded = true
 				break
 			}
 		}
+		if !excluded {
+			match[k] = v
+		}
 	}
 	return match
 }
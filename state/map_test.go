@@ -0,0 +1,89 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { Path = path }(Path)
+	Path = filepath.Join(dir, "state")
+
+	when := time.Unix(1600000000, 123456789).UTC()
+	saved := Map{"/usr/share/fonts": when}
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	loaded := Load()
+	if len(loaded) != 1 || !loaded["/usr/share/fonts"].Equal(when) {
+		t.Fatalf("Load: expected %v, got %v", saved, loaded)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	defer func(path string) { Path = path }(Path)
+	Path = filepath.Join(os.TempDir(), "usysconf-state-missing", "state")
+	if m := Load(); m == nil || len(m) != 0 {
+		t.Fatalf("expected an empty Map, got %v", m)
+	}
+	if _, err := Read(); !os.IsNotExist(err) {
+		t.Fatalf("expected Read to fail for a missing state, got %v", err)
+	}
+}
+
+func TestReadCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { Path = path }(Path)
+	Path = filepath.Join(dir, "state")
+	if err = ioutil.WriteFile(Path, []byte("not cbor"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Read(); err == nil {
+		t.Fatal("expected Read to fail for a corrupt state")
+	}
+}
+
+func TestExclude(t *testing.T) {
+	when := time.Now()
+	m := Map{
+		"/var/cache/a/keep": when,
+		"/var/cache/a/drop": when,
+		"/var/cache/b/drop": when,
+	}
+	kept := m.Exclude([]string{"/var/cache/a/drop", "/var/cache/b/*"})
+	if len(kept) != 1 || !kept["/var/cache/a/keep"].Equal(when) {
+		t.Errorf("expected only '/var/cache/a/keep' to be kept, got %v", kept)
+	}
+	if len(m) != 3 {
+		t.Errorf("expected the original Map to be left alone, got %v", m)
+	}
+	if all := m.Exclude(nil); len(all) != 3 {
+		t.Errorf("expected every key without patterns, got %v", all)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Export
+const SnapshotVersion = 1
+
+// Snapshot is a portable copy of a state, i.e. for restoring a test system to a known state
+type Snapshot struct {
+	// Version is the format of the snapshot, newer versions are refused by Import
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	Entries Map       `json:"entries"`
+}
+
+// Export writes the state to a versioned JSON snapshot
+func (m Map) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(Snapshot{
+		Version: SnapshotVersion,
+		Time:    time.Now().UTC(),
+		Entries: m,
+	})
+}
+
+// Import reads a state from a snapshot written by Export
+func Import(r io.Reader) (Map, error) {
+	var snap Snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, err
+	}
+	if snap.Version < 1 || snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, must be between 1 and %d", snap.Version, SnapshotVersion)
+	}
+	if snap.Entries == nil {
+		snap.Entries = make(Map)
+	}
+	return snap.Entries, nil
+}
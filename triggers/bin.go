@@ -16,73 +16,487 @@ package triggers
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	log "github.com/DataDrake/waterlog"
 	"github.com/getsolus/usysconf/util"
-	"os/exec"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// errBudget is the reason for stopping the bins of a trigger which ran out of time
+var errBudget = errors.New("trigger budget exceeded")
+
 // Bin contains the details of the binary to be executed.
 type Bin struct {
 	Task    string   `toml:"task"`
 	Bin     string   `toml:"bin"`
 	Args    []string `toml:"args"`
 	Replace *Replace `toml:"replace"`
+	Retry   *Retry   `toml:"retry"`
+	// WorkingDir is the directory the bin is run from, relative Replace paths are globbed within it
+	WorkingDir string `toml:"working_dir"`
+	// When limits the bin to a Scope or environment, see When for how it relates to Skip
+	When *When `toml:"when,omitempty"`
+	// Condition limits the bin to when a file contains (or lacks) a matching line
+	Condition *Condition `toml:"condition,omitempty"`
+	// Description explains why a Bin exists, it is ignored at runtime and only shown by "list --verbose"
+	Description string `toml:"description"`
+	// Dangerous marks a Bin as destructive, requiring confirmation when asked for
+	Dangerous bool `toml:"dangerous"`
+	// Umask is the octal file mode creation mask for the process, i.e. "022"
+	Umask string `toml:"umask"`
+	// PIDNamespace runs the bin in a new PID namespace (root only), so that any processes it leaves
+	// behind are killed along with it
+	PIDNamespace bool `toml:"pid_namespace"`
+	// Coalesce defers the bin until every trigger has run, then runs it once for all of the bins
+	// sharing this identifier, i.e. "daemon-reload". Until then its output is Skipped, so it does
+	// not count as changes for RunAfterChanged, and its outcome is then given to every trigger which
+	// requested it.
+	Coalesce string `toml:"coalesce"`
+	// FailOnStderr fails the bin when it prints anything to stderr, even with a zero exit code
+	FailOnStderr bool `toml:"fail_on_stderr"`
+	// ExpectOutput fails the bin when its combined stdout and stderr do not match, even with a zero
+	// exit code. It is only checked once FailOnStderr has passed, so a confirmation printed to stderr
+	// can only be expected without FailOnStderr.
+	ExpectOutput string `toml:"expect_output"`
+	// Timeout limits how long each run of the bin may take, i.e. "5m" (default: Scope.DefaultTimeout)
+	Timeout string `toml:"timeout"`
+	// RequireMatch fails the trigger when the Replace paths match nothing, instead of running nothing
+	RequireMatch bool `toml:"require_match"`
+	// OOMScoreAdj makes the bin more (up to 1000) or less (down to -1000) likely to be killed when
+	// the system runs out of memory, i.e. 500 for rebuilding a cache. It is applied as soon as the
+	// process has started, so anything it spawns before then keeps the inherited value. Since 0 is
+	// the same as not setting it, the inherited value cannot be reset to exactly 0, use 1 or -1 instead.
+	OOMScoreAdj int `toml:"oom_score_adj"`
+	// BatchSize passes up to this many of the Replace paths to each invocation, replacing "***" with
+	// one argument for each path, rather than running once for every path (default: 1)
+	BatchSize int `toml:"batch_size"`
+
+	umask        *int
+	expectOutput *regexp.Regexp
+	fanOut       string
+	timeout      time.Duration
+	deadline     time.Time
+	cgroup       string
+	log          io.Writer
+}
+
+// Validate checks for errors in a Bin configuration
+func (b *Bin) Validate() error {
+	if len(b.Umask) > 0 {
+		mask, err := strconv.ParseUint(b.Umask, 8, 32)
+		if err != nil || mask > 0777 {
+			return fmt.Errorf("invalid umask '%s', must be octal between 000 and 777", b.Umask)
+		}
+		umask := int(mask)
+		b.umask = &umask
+	}
+	if len(b.Timeout) > 0 {
+		var err error
+		if b.timeout, err = time.ParseDuration(b.Timeout); err != nil || b.timeout <= 0 {
+			return fmt.Errorf("invalid timeout '%s'", b.Timeout)
+		}
+	}
+	if len(b.ExpectOutput) > 0 {
+		var err error
+		if b.expectOutput, err = regexp.Compile(b.ExpectOutput); err != nil {
+			return fmt.Errorf("invalid expect_output '%s', reason: %s", b.ExpectOutput, err)
+		}
+	}
+	if err := b.When.Validate(); err != nil {
+		return err
+	}
+	if err := b.Condition.Validate(); err != nil {
+		return err
+	}
+	if b.OOMScoreAdj < util.MinOOMScoreAdj || b.OOMScoreAdj > util.MaxOOMScoreAdj {
+		return fmt.Errorf("oom_score_adj must be between %d and %d", util.MinOOMScoreAdj, util.MaxOOMScoreAdj)
+	}
+	if b.BatchSize < 0 {
+		return fmt.Errorf("batch_size must not be negative")
+	}
+	if b.Retry != nil {
+		return b.Retry.Validate()
+	}
+	return nil
 }
 
-// ExecuteBins generates and runs all of the necesarry Bin commands
+// AppendArgs adds extra arguments to the end of every Bin in the trigger
+func (t *Trigger) AppendArgs(args []string) {
+	for i := range t.Bins {
+		b := &t.Bins[i]
+		b.Args = append(append([]string{}, b.Args...), args...)
+	}
+}
+
+// ExecuteBins generates and runs all of the necesarry Bin commands. Bins are run in the order they
+// are declared, with every invocation of a fanned-out Bin completing before the next Bin starts.
+// With Scope.PerDevice, the invocations of a fanned-out Bin are run in parallel across devices.
+// A Native trigger is run instead of any bins.
 func (t *Trigger) ExecuteBins(s Scope) {
+	if t.native != nil {
+		t.executeNative(s)
+		return
+	}
 	var bins []Bin
 	var outputs []Output
 	// Generate
-	for _, b := range t.Bins {
+	for index, b := range t.Bins {
+		b.Args = t.FillPlaceholders(b.Args)
 		bs, outs := b.FanOut()
+		if len(bs) == 0 && b.RequireMatch {
+			t.Output = append(t.Output, Output{
+				TriggerName: t.Name,
+				Bin:         index + 1,
+				Name:        b.Task,
+				Status:      Failure,
+				Message:     fmt.Sprintf("replace paths matching nothing for '%s'", b.Bin),
+			})
+			continue
+		}
+		for i := range outs {
+			outs[i].TriggerName = t.Name
+			outs[i].Bin = index + 1
+		}
 		bins = append(bins, bs...)
 		outputs = append(outputs, outs...)
 	}
-	// Execute
-	for i, b := range bins {
-		out := b.Execute(s, t.Env)
-		outputs[i].Status = out.Status
-		outputs[i].Message = out.Message
+	// Apply resource limits
+	if t.CPU != nil && !s.DryRun {
+		if cg := t.CPU.Cgroup(t.Name); len(cg) > 0 {
+			for i := range bins {
+				bins[i].cgroup = cg
+			}
+		}
+	}
+	// Send output to the log file
+	if len(t.LogFile) > 0 && !s.DryRun {
+		if f := t.openLog(); f != nil {
+			defer f.Close()
+			for i := range bins {
+				bins[i].log = f
+			}
+		}
+	}
+	// Resolve the environment
+	env, err := t.Environment(s)
+	if err != nil {
+		t.Output = append(t.Output, Output{
+			Status:  Failure,
+			Message: err.Error(),
+		})
+		return
+	}
+	// Bound the time taken by all of the bins together
+	var deadline time.Time
+	if t.timeout > 0 {
+		deadline = time.Now().Add(t.timeout)
+	}
+	// Execute, one Bin at a time
+	for i := 0; i < len(bins); {
+		// Find the invocations fanned out from the same Bin
+		j := i + 1
+		for j < len(bins) && outputs[j].Bin == outputs[i].Bin {
+			j++
+		}
+		if s.PerDevice && j-i > 1 && len(bins[i].Coalesce) == 0 {
+			t.executeDevices(s, env, bins[i:j], outputs[i:j], deadline)
+		} else {
+			for k := i; k < j; k++ {
+				t.executeBin(s, env, bins[k], &outputs[k], deadline, nil)
+			}
+		}
+		i = j
 	}
 	t.Output = append(t.Output, outputs...)
 }
 
+// executeBin runs a single invocation of a Bin, filling in its output. When invocations are run
+// concurrently, everything printed about this one is printed together once it has finished, while
+// holding the print lock.
+func (t *Trigger) executeBin(s Scope, env map[string]string, b Bin, out *Output, deadline time.Time, printLock *sync.Mutex) {
+	if s.interrupted() {
+		out.Status = Skipped
+		out.Message = "the run being interrupted"
+		return
+	}
+	if reason, ok := b.When.Reason(s, env); !ok {
+		out.Status = Skipped
+		out.Message = reason
+		return
+	}
+	reason, ok, err := b.Condition.Reason()
+	if err != nil {
+		out.Status = Failure
+		out.Message = err.Error()
+		return
+	}
+	if !ok {
+		out.Status = Skipped
+		out.Message = reason
+		return
+	}
+	if !deadline.IsZero() {
+		if !time.Now().Before(deadline) {
+			out.Status = Skipped
+			out.Message = errBudget.Error()
+			return
+		}
+		b.deadline = deadline
+	}
+	if len(t.BinPath) > 0 {
+		path, err := t.lookPath(b.Bin)
+		if err != nil {
+			out.Status = Failure
+			out.Message = fmt.Sprintf("unable to find '%s', reason: %s", b.Bin, err)
+			return
+		}
+		b.Bin = path
+	}
+	if len(b.Coalesce) > 0 {
+		t.deferBin(b, env)
+		out.Status = Skipped
+		out.Message = fmt.Sprintf("waiting to run once as '%s', after every trigger", b.Coalesce)
+		out.coalesced = b.Coalesce
+		return
+	}
+	report := func() {
+		if s.DumpEnv {
+			t.dumpEnv(b, env)
+		}
+		if s.ExplainEnv {
+			t.explainEnv(s, b)
+		}
+		if s.Verbosity >= VerbosityCommands {
+			log.Infof("    Running '%s'\n", t.MaskText(b.Bin+" "+strings.Join(b.Args, " ")))
+		}
+	}
+	if printLock == nil {
+		report()
+	}
+	res := b.Execute(s, env)
+	if printLock != nil {
+		printLock.Lock()
+		defer printLock.Unlock()
+		report()
+	}
+	if s.Verbosity >= VerbosityOutput && len(res.output) > 0 {
+		for _, line := range strings.Split(strings.TrimRight(t.MaskText(string(res.output)), "\n"), "\n") {
+			log.Infof("        %s\n", line)
+		}
+	}
+	out.Status = res.Status
+	out.Message = res.Message
+	out.Usage = res.Usage
+	out.Duration = res.Duration
+	out.output = res.output
+}
+
+// dumpEnv prints the environment a Bin will receive, with masking applied, including the fan-out
+// variable, built the same way as by run
+func (t *Trigger) dumpEnv(b Bin, env map[string]string) {
+	pairs := b.environment(env)
+	if pairs == nil {
+		pairs = os.Environ()
+	}
+	log.Infof("    Environment for '%s':\n", t.MaskText(fmt.Sprintf("%s %v", b.Bin, b.Args)))
+	for _, kv := range t.MaskEnv(pairs) {
+		log.Infof("        %s\n", kv)
+	}
+}
+
+// explainEnv prints the environment a Bin will receive along with the origin of each variable, with
+// masking applied
+func (t *Trigger) explainEnv(s Scope, b Bin) {
+	vars, err := t.ExplainEnv(s, b)
+	if err != nil {
+		log.Warnf("    Failed to explain environment for '%s', reason: %s\n", t.MaskText(fmt.Sprintf("%s %v", b.Bin, b.Args)), err)
+		return
+	}
+	log.Infof("    Environment sources for '%s':\n", t.MaskText(fmt.Sprintf("%s %v", b.Bin, b.Args)))
+	for _, v := range vars {
+		if t.masked(v.Key) {
+			v.Value = maskValue
+		}
+		log.Infof("        %s=%s (%s)\n", v.Key, v.Value, v.Origin)
+	}
+}
+
+// openLog opens the log file of a trigger for appending, returning nil on failure
+func (t *Trigger) openLog() *os.File {
+	path, ok := t.Expand(t.LogFile)
+	if !ok {
+		log.Warnf("Failed to expand log file '%s'\n", t.LogFile)
+		return nil
+	}
+	path = filepath.Clean(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		log.Warnf("Failed to create directory for log file '%s', reason: %s\n", path, err)
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		log.Warnf("Failed to open log file '%s', reason: %s\n", path, err)
+		return nil
+	}
+	return f
+}
+
 // Execute the binary from the confuration
-func (b *Bin) Execute(s Scope, env map[string]string) Output {
-	out := Output{Status: Success}
+func (b *Bin) Execute(s Scope, env map[string]string) (out Output) {
+	out.Status = Success
 	// if the norun flag is present do not execute the configuration
 	if s.DryRun {
 		out.Status = Success
 		return out
 	}
-	// Create command
-	cmd := exec.Command(b.Bin, b.Args...)
-	// Setup environment
-	for k, v := range env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-	}
-	// Add buffer for output
-	var buff bytes.Buffer
-	cmd.Stdout = &buff
-	cmd.Stderr = &buff
-	// Run the command
-	if err := cmd.Run(); err != nil {
+	// Run the command, retrying as needed
+	start := time.Now()
+	defer func() {
+		out.Duration = time.Since(start)
+	}()
+	var res Result
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = b.run(s, env)
+		if err == nil && b.FailOnStderr && len(bytes.TrimSpace(res.Stderr)) > 0 {
+			err = fmt.Errorf("printed to stderr: %s", bytes.TrimSpace(tail(res.Stderr, s.MaxOutputLines)))
+		}
+		if err == nil && b.expectOutput != nil && !b.expectOutput.Match(res.Output) {
+			err = fmt.Errorf("output did not match '%s'", b.ExpectOutput)
+		}
+		output := res.Output
+		if b.Retry == nil || err == errBudget || err == errInterrupted {
+			break
+		}
+		err = b.Retry.Check(err, output)
+		if attempt >= b.Retry.Attempts || !b.Retry.ShouldRetry(err, output) {
+			break
+		}
+		if !s.RetryBudget.take() {
+			break
+		}
+		log.Debugf("    Retrying '%s' (attempt %d of %d), reason: %s\n", b.Bin, attempt+2, b.Retry.Attempts+1, err)
+		time.Sleep(b.Retry.delay)
+	}
+	out.Usage = res.Usage
+	out.output = res.Output
+	if err != nil {
 		out.Status = Failure
-		out.Message = fmt.Sprintf("error executing '%s %v': %s\n%s", b.Bin, b.Args, err.Error(), buff.String())
+		out.Message = fmt.Sprintf("error executing '%s %v': %s\n%s", b.Bin, b.Args, err.Error(), tail(res.Output, s.MaxOutputLines))
+		// Keep the console short when the output has been logged
+		if b.log != nil {
+			out.Message = fmt.Sprintf("error executing '%s %v': %s, see the log file for output", b.Bin, b.Args, err.Error())
+		}
 	}
 	return out
 }
 
+// environ converts an environment into sorted KEY=VALUE pairs, nil if the environment is inherited
+func environ(env map[string]string) (pairs []string) {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+	return
+}
+
+// resolve makes relative patterns relative to the WorkingDir, if there is one
+func (b *Bin) resolve(patterns []string) []string {
+	if len(b.WorkingDir) == 0 {
+		return patterns
+	}
+	resolved := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(b.WorkingDir, pattern)
+		}
+		resolved = append(resolved, pattern)
+	}
+	return resolved
+}
+
+// environment gets the KEY=VALUE pairs passed to the binary, nil if the environment is inherited
+func (b *Bin) environment(env map[string]string) []string {
+	pairs := environ(env)
+	if len(b.fanOut) > 0 {
+		if pairs == nil {
+			pairs = os.Environ()
+		}
+		pairs = append(pairs, b.Replace.EnvName()+"="+b.fanOut)
+	}
+	return pairs
+}
+
+// run executes the binary a single time
+func (b *Bin) run(s Scope, env map[string]string) (Result, error) {
+	c := Command{
+		Bin:    b.Bin,
+		Args:   b.Args,
+		Cgroup: b.cgroup,
+		Dir:    b.WorkingDir,
+		Log:    b.log,
+		Umask:  b.umask,
+
+		PIDNamespace:  b.PIDNamespace,
+		CaptureStderr: b.FailOnStderr,
+		OOMScoreAdj:   b.OOMScoreAdj,
+	}
+	if b.log != nil {
+		fmt.Fprintf(b.log, "==> %s: %s %s\n", time.Now().Format(time.RFC3339), b.Bin, strings.Join(b.Args, " "))
+	}
+	c.Env = b.environment(env)
+	timeout := b.timeout
+	if timeout == 0 {
+		timeout = s.DefaultTimeout
+	}
+	ctx := s.runContext()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if !b.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, b.deadline)
+		defer cancel()
+	}
+	res, err := s.executor().Run(ctx, c)
+	if s.interrupted() {
+		err = errInterrupted
+	} else if ctx.Err() == context.DeadlineExceeded {
+		if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+			err = errBudget
+		} else {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+	}
+	return res, err
+}
+
 // FanOut generates one or more bin tasks from a given, as needed by replacing the "***" sequence
-// in the arguments and creating separate binaries to be executed.
+// in the arguments and creating separate binaries to be executed. Invocations are generated in
+// sorted order of the matching paths, so the result is the same across repeated calls. With a
+// BatchSize, each invocation receives the next batch of paths, in the same order.
 func (b Bin) FanOut() (nbins []Bin, outputs []Output) {
 
 	r := b.Replace
 
-	phExists := false
-	phIndex := 0
+	phExists := r != nil && len(r.Env) > 0
+	phIndex := -1
 	for i, arg := range b.Args {
 		if r == nil {
 			break
@@ -102,16 +516,46 @@ func (b Bin) FanOut() (nbins []Bin, outputs []Output) {
 		return
 	}
 
-	log.Debugf("    Replace string exists at arg: %d\n", phIndex)
+	if phIndex >= 0 {
+		log.Debugf("    Replace string exists at arg: %d\n", phIndex)
+	}
 
-	paths := util.FilterPaths(r.Paths, r.Exclude)
+	paths := util.FilterPaths(b.resolve(r.Paths), b.resolve(r.Exclude))
+	seen := make(map[string]bool)
+	matched := make([]string, 0, len(paths))
 	for _, p := range paths {
+		if r.Dedupe {
+			if seen[p] {
+				log.Debugf("    Skipping duplicate path '%s'\n", p)
+				continue
+			}
+			seen[p] = true
+		}
+		matched = append(matched, p)
+	}
+	size := 1
+	if b.BatchSize > 0 {
+		size = b.BatchSize
+	}
+	for start := 0; start < len(matched); start += size {
+		end := start + size
+		if end > len(matched) {
+			end = len(matched)
+		}
+		batch := matched[start:end]
 		out := Output{
 			Name:    b.Task,
-			SubTask: p,
+			SubTask: batch[0],
 		}
-		b.Args[phIndex] = p
-		nbins = append(nbins, b)
+		if len(batch) > 1 {
+			out.SubTask = fmt.Sprintf("%s (and %d more)", batch[0], len(batch)-1)
+		}
+		nb := b
+		if phIndex >= 0 {
+			nb.Args = append(append(append([]string{}, b.Args[:phIndex]...), batch...), b.Args[phIndex+1:]...)
+		}
+		nb.fanOut = strings.Join(batch, "\n")
+		nbins = append(nbins, nb)
 		outputs = append(outputs, out)
 	}
 	return
@@ -0,0 +1,208 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fannedArgs gets the arguments of every invocation of a fanned-out bin, relative to dir
+func fannedArgs(t *testing.T, dir string, bins []Bin) (args []string) {
+	for _, b := range bins {
+		for _, arg := range b.Args {
+			rel, err := filepath.Rel(dir, arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args = append(args, rel)
+		}
+	}
+	return
+}
+
+func TestFanOutDedupe(t *testing.T) {
+	dir, cleanup := testTree(t, "a.conf", "b.conf", "b.txt")
+	defer cleanup()
+	tests := []struct {
+		dedupe   bool
+		expected []string
+	}{
+		{false, []string{"a.conf", "b.conf", "b.conf", "b.txt"}},
+		{true, []string{"a.conf", "b.conf", "b.txt"}},
+	}
+	for _, test := range tests {
+		b := Bin{
+			Bin:  "/bin/touch",
+			Args: []string{"***"},
+			Replace: &Replace{
+				Paths:  []string{filepath.Join(dir, "*.conf"), filepath.Join(dir, "b.*")},
+				Dedupe: test.dedupe,
+			},
+		}
+		bins, outs := b.FanOut()
+		if len(bins) != len(outs) {
+			t.Fatalf("dedupe %t: expected an output for each of %d bins, got %d", test.dedupe, len(bins), len(outs))
+		}
+		args := fannedArgs(t, dir, bins)
+		if strings.Join(args, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("dedupe %t: expected %v, got %v", test.dedupe, test.expected, args)
+		}
+	}
+}
+
+func TestFanOutSorted(t *testing.T) {
+	dir, cleanup := testTree(t, "a", "b", "c")
+	defer cleanup()
+	b := Bin{
+		Bin:     "/bin/touch",
+		Args:    []string{"***"},
+		Replace: &Replace{Paths: []string{filepath.Join(dir, "c"), filepath.Join(dir, "[ab]")}},
+	}
+	for i := 0; i < 5; i++ {
+		bins, outs := b.FanOut()
+		args := fannedArgs(t, dir, bins)
+		if strings.Join(args, " ") != "a b c" {
+			t.Fatalf("expected the paths in sorted order, got %v", args)
+		}
+		for j, out := range outs {
+			if out.SubTask != bins[j].Args[0] {
+				t.Errorf("expected the output of '%s', got '%s'", bins[j].Args[0], out.SubTask)
+			}
+		}
+	}
+}
+
+func TestExecuteBinsOrder(t *testing.T) {
+	dir, cleanup := testTree(t, "b", "a")
+	defer cleanup()
+	exec := &fakeExecutor{}
+	tr := Trigger{
+		Name: "order",
+		Bins: []Bin{
+			{Bin: "/bin/first"},
+			{Bin: "/bin/each", Args: []string{"-v", "***"}, Replace: &Replace{Paths: []string{filepath.Join(dir, "*")}}},
+		},
+	}
+	tr.ExecuteBins(Scope{Executor: exec})
+	expected := []string{
+		"/bin/first",
+		"/bin/each -v " + filepath.Join(dir, "a"),
+		"/bin/each -v " + filepath.Join(dir, "b"),
+	}
+	if strings.Join(exec.ran(), "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(exec.ran(), "\n"))
+	}
+	for _, out := range tr.Output {
+		if out.Status != Success {
+			t.Errorf("expected '%s' to succeed, got %s", out.SubTask, out.Status)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	b := Bin{WorkingDir: "/srv/work"}
+	resolved := b.resolve([]string{"*.conf", "sub/../x", "/etc/abs"})
+	expected := []string{"/srv/work/*.conf", "/srv/work/x", "/etc/abs"}
+	if strings.Join(resolved, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, resolved)
+	}
+	b.WorkingDir = ""
+	if resolved := b.resolve([]string{"*.conf"}); resolved[0] != "*.conf" {
+		t.Errorf("expected the pattern to be kept without a working directory, got %v", resolved)
+	}
+}
+
+func TestFanOutWorkingDir(t *testing.T) {
+	dir, cleanup := testTree(t, "work/", "work/a.conf", "work/b.conf", "other/", "other/c.conf")
+	defer cleanup()
+	b := Bin{
+		Bin:        "/bin/touch",
+		Args:       []string{"***"},
+		WorkingDir: filepath.Join(dir, "work"),
+		Replace: &Replace{
+			Paths:   []string{"*.conf", filepath.Join(dir, "other", "*.conf")},
+			Exclude: []string{"b.conf"},
+		},
+	}
+	bins, _ := b.FanOut()
+	args := fannedArgs(t, dir, bins)
+	expected := []string{"other/c.conf", "work/a.conf"}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestExecuteBinsAfterFanOut(t *testing.T) {
+	dir, cleanup := testTree(t, "a", "b", "c")
+	defer cleanup()
+	for _, perDevice := range []bool{false, true} {
+		exec := &fakeExecutor{}
+		tr := Trigger{
+			Name: "order",
+			Bins: []Bin{
+				{Bin: "/bin/generate"},
+				{Bin: "/bin/each", Args: []string{"***"}, Replace: &Replace{Paths: []string{filepath.Join(dir, "*")}}},
+				{Bin: "/bin/index"},
+				{Bin: "/bin/finish"},
+			},
+		}
+		tr.ExecuteBins(Scope{Executor: exec, PerDevice: perDevice})
+		ran := exec.ran()
+		if len(ran) != 6 {
+			t.Fatalf("per-device %t: expected 6 commands, got %v", perDevice, ran)
+		}
+		if ran[0] != "/bin/generate" {
+			t.Errorf("per-device %t: expected the first bin to run first, got %v", perDevice, ran)
+		}
+		for _, line := range ran[1:4] {
+			if !strings.HasPrefix(line, "/bin/each ") {
+				t.Errorf("per-device %t: expected every invocation of the fan-out before the next bin, got %v", perDevice, ran)
+				break
+			}
+		}
+		if ran[4] != "/bin/index" || ran[5] != "/bin/finish" {
+			t.Errorf("per-device %t: expected the bins after the fan-out in order, got %v", perDevice, ran)
+		}
+		var bins []int
+		for _, out := range tr.Output {
+			bins = append(bins, out.Bin)
+		}
+		if fmt.Sprint(bins) != "[1 2 2 2 3 4]" {
+			t.Errorf("per-device %t: expected the outputs in the order of the bins, got %v", perDevice, bins)
+		}
+	}
+}
+
+func TestValidateOOMScoreAdj(t *testing.T) {
+	for _, tc := range []struct {
+		adj   int
+		valid bool
+	}{
+		{0, true},
+		{-1000, true},
+		{1000, true},
+		{500, true},
+		{-1001, false},
+		{1001, false},
+	} {
+		b := Bin{Task: "oom", Bin: "/usr/bin/true", OOMScoreAdj: tc.adj}
+		if err := b.Validate(); (err == nil) != tc.valid {
+			t.Errorf("expected oom_score_adj %d to be valid: %t, got %v", tc.adj, tc.valid, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io"
+	"time"
+)
+
+// bundleScope is the part of a Scope which is recorded in a bundle
+type bundleScope struct {
+	Chroot bool `json:"chroot"`
+	Debug  bool `json:"debug"`
+	DryRun bool `json:"dry_run"`
+	Forced bool `json:"forced"`
+	Live   bool `json:"live"`
+	Strict bool `json:"strict"`
+}
+
+// WriteBundle renders a gzipped tarball for bug reports, containing the detected scope, the
+// summary of the run, and the configuration and full output of every trigger. The values of
+// masked variables are hidden throughout.
+func WriteBundle(w io.Writer, s Scope, results []Trigger, summary Summary) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	b := bundle{tw: tw, now: time.Now()}
+	b.writeJSON("scope.json", bundleScope{
+		Chroot: s.Chroot,
+		Debug:  s.Debug,
+		DryRun: s.DryRun,
+		Forced: s.Forced,
+		Live:   s.Live,
+		Strict: s.Strict,
+	})
+	b.writeJSON("summary.json", summary)
+	for _, t := range results {
+		b.writeConfig(t)
+		b.writeOutput(t)
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// bundle adds files to a tarball, stopping at the first error
+type bundle struct {
+	tw  *tar.Writer
+	now time.Time
+	err error
+}
+
+// write adds a single file
+func (b *bundle) write(name string, data []byte) {
+	if b.err != nil {
+		return
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if b.err = b.tw.WriteHeader(hdr); b.err != nil {
+		return
+	}
+	_, b.err = b.tw.Write(data)
+}
+
+// writeJSON adds a file containing an indented JSON document
+func (b *bundle) writeJSON(name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		b.err = err
+		return
+	}
+	b.write(name, append(data, '\n'))
+}
+
+// writeConfig adds the configuration of a trigger, as it was loaded
+func (b *bundle) writeConfig(t Trigger) {
+	if len(t.Path) == 0 {
+		return
+	}
+	if len(t.Env) > 0 {
+		env := make(map[string]string)
+		for k, v := range t.Env {
+			if t.masked(k) {
+				v = maskValue
+			}
+			env[k] = v
+		}
+		t.Env = env
+	}
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, "# %s\n", t.Path)
+	if err := toml.NewEncoder(&buff).Encode(t); err != nil {
+		b.err = err
+		return
+	}
+	b.write("configs/"+t.Name+".toml", buff.Bytes())
+}
+
+// writeOutput adds the status and output of every task of a trigger
+func (b *bundle) writeOutput(t Trigger) {
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, "%s: %s\n", t.Name, t.Status())
+	for _, out := range t.Output {
+		fmt.Fprintf(&buff, "\n==> %s", out.Name)
+		if len(out.SubTask) > 0 {
+			fmt.Fprintf(&buff, " [%s]", out.SubTask)
+		}
+		fmt.Fprintf(&buff, ": %s (%s)\n", out.Status, out.Duration)
+		if len(out.Message) > 0 {
+			fmt.Fprintf(&buff, "%s\n", t.MaskText(out.Message))
+		}
+		if len(out.output) > 0 {
+			fmt.Fprintf(&buff, "%s\n", t.MaskText(string(out.output)))
+		}
+	}
+	b.write("output/"+t.Name+".log", buff.Bytes())
+}
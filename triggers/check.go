@@ -15,33 +15,176 @@
 package triggers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/getsolus/usysconf/state"
 	log "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/state"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// defaultExecTimeout limits how long a Check.Exec may run when no timeout is specified
+const defaultExecTimeout = 10 * time.Second
+
+// defaultCheckDelay is the pause between attempts to resolve the check paths when no delay is specified
+const defaultCheckDelay = time.Second
+
 // Check contains paths that must exixt to execute the configuration.  This
 // supports globbing.
+//
+// Attempts allows paths on slow filesystems (i.e. NFS or autofs) to be scanned again, waiting
+// Delay in between, when none of them could be found.
 type Check struct {
-	Paths []string `toml:"paths"`
+	Paths    []string `toml:"paths"`
+	Exec     *Exec    `toml:"exec,omitempty"`
+	Version  *Version `toml:"version,omitempty"`
+	Attempts int      `toml:"attempts"`
+	Delay    string   `toml:"delay"`
+	// WatchPaths limits a "--since-last-run" to running the trigger when one of them was modified
+	// since its last success
+	WatchPaths []string `toml:"watch_paths"`
+	// Modules are the kernel modules which must be loaded, or built in, for the trigger to run
+	Modules []string `toml:"modules"`
+
+	delay time.Duration
+}
+
+// Exec contains a command which must exit with one of the expected codes for a trigger to run
+type Exec struct {
+	Bin  string   `toml:"bin"`
+	Args []string `toml:"args"`
+	// Codes are the acceptable exit codes, defaulting to 0
+	Codes []int `toml:"codes"`
+	// Timeout limits how long the command may run, defaulting to 10s
+	Timeout string `toml:"timeout"`
+
+	timeout time.Duration
+}
+
+// Validate checks for errors in a Check configuration
+func (c *Check) Validate() error {
+	if c.Attempts < 0 {
+		return fmt.Errorf("check attempts must not be negative")
+	}
+	c.delay = defaultCheckDelay
+	if len(c.Delay) > 0 {
+		var err error
+		if c.delay, err = time.ParseDuration(c.Delay); err != nil {
+			return fmt.Errorf("invalid check delay '%s', reason: %s", c.Delay, err)
+		}
+	}
+	if c.Version != nil {
+		if err := c.Version.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Exec == nil {
+		return nil
+	}
+	if len(c.Exec.Bin) == 0 {
+		return fmt.Errorf("check exec must specify a bin")
+	}
+	c.Exec.timeout = defaultExecTimeout
+	if len(c.Exec.Timeout) > 0 {
+		var err error
+		if c.Exec.timeout, err = time.ParseDuration(c.Exec.Timeout); err != nil {
+			return fmt.Errorf("invalid check exec timeout '%s', reason: %s", c.Exec.Timeout, err)
+		}
+		if c.Exec.timeout <= 0 {
+			return fmt.Errorf("invalid check exec timeout '%s', it must be positive", c.Exec.Timeout)
+		}
+	}
+	return nil
+}
+
+// Run executes the command, returning a reason if it did not exit with an expected code
+func (e *Exec) Run(s Scope) (reason string, ok bool) {
+	ctx, cancel := context.WithTimeout(s.runContext(), e.timeout)
+	defer cancel()
+	_, err := s.executor().Run(ctx, Command{Bin: e.Bin, Args: e.Args})
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Sprintf("check '%s' timing out after %s", e.Bin, e.timeout), false
+	}
+	code := 0
+	if err != nil {
+		var exit *exec.ExitError
+		if !errors.As(err, &exit) {
+			return fmt.Sprintf("check '%s' failing to run, reason: %s", e.Bin, err), false
+		}
+		code = exit.ExitCode()
+	}
+	codes := e.Codes
+	if len(codes) == 0 {
+		codes = []int{0}
+	}
+	for _, c := range codes {
+		if c == code {
+			return "", true
+		}
+	}
+	return fmt.Sprintf("check '%s' exiting with code %d", e.Bin, code), false
 }
 
-// CheckMatch will glob the paths and if the path does not exist in the system, an error is returned
-func (t *Trigger) CheckMatch() (m state.Map, ok bool) {
+// CheckMatch will glob the paths and if the path does not exist in the system, an error is returned.
+// Paths which cannot be resolved are a Failure, or a CheckError with Scope.FailOnCheckError. Paths
+// which match nothing since one of their directories cannot be read are skipped instead, unless
+// with Scope.FailOnCheckError.
+func (t *Trigger) CheckMatch(s Scope) (m state.Map, ok bool) {
 	if t.Check == nil {
 		log.Debugf("No check paths for trigger '%s'\n", t.Name)
 		ok = true
 		return
 	}
 	m, err := state.Scan(t.Check.Paths)
+	for attempt := 1; err == nil && len(m) == 0 && len(t.Check.Paths) > 0 && attempt <= t.Check.Attempts; attempt++ {
+		log.Debugf("No check paths found for trigger '%s', retrying in %s (%d/%d)\n", t.Name, t.Check.delay, attempt, t.Check.Attempts)
+		time.Sleep(t.Check.delay)
+		m, err = state.Scan(t.Check.Paths)
+	}
+	if err == nil && len(m) == 0 {
+		if err = t.Check.unreadable(); err != nil && !s.FailOnCheckError {
+			t.Output = append(t.Output, Output{
+				Status:  Skipped,
+				Message: fmt.Sprintf("check paths failing to resolve, reason: %s", err),
+			})
+			return
+		}
+	}
 	if err != nil {
 		out := Output{
 			Status:  Failure,
 			Message: fmt.Sprintf("Failed to scan paths for '%s', reason: %s\n", t.Name, err),
 		}
+		if s.FailOnCheckError {
+			out.Status = CheckError
+		}
 		t.Output = append(t.Output, out)
 		return
 	}
 	ok = true
 	return
 }
+
+// unreadable finds a directory of the check paths which cannot be read due to its permissions,
+// which leaves the paths within it matching nothing rather than failing to be resolved
+func (c *Check) unreadable() error {
+	for _, pattern := range c.Paths {
+		dir := filepath.Dir(pattern)
+		if strings.ContainsAny(dir, `*?[\`) {
+			continue
+		}
+		f, err := os.Open(dir)
+		if err == nil {
+			_, err = f.Readdirnames(1)
+			_ = f.Close()
+		}
+		if err != nil && os.IsPermission(err) {
+			return fmt.Errorf("unable to read directory '%s': %s", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"testing"
+)
+
+func TestCheckValidateExecTimeout(t *testing.T) {
+	tests := []struct {
+		timeout string
+		valid   bool
+	}{
+		{"", true},
+		{"5s", true},
+		{"0s", false},
+		{"-1s", false},
+		{"soon", false},
+	}
+	for _, test := range tests {
+		c := Check{Exec: &Exec{Bin: "/bin/true", Timeout: test.timeout}}
+		if err := c.Validate(); (err == nil) != test.valid {
+			t.Errorf("timeout '%s': expected valid to be %t, got %v", test.timeout, test.valid, err)
+		}
+	}
+}
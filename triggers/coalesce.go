@@ -0,0 +1,88 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	log "github.com/DataDrake/waterlog"
+	"strings"
+)
+
+// deferredBin is a coalesced Bin waiting to be run, along with its environment
+type deferredBin struct {
+	bin Bin
+	env map[string]string
+}
+
+// deferBin holds on to a coalesced Bin until every trigger has run
+func (t *Trigger) deferBin(b Bin, env map[string]string) {
+	log.Debugf("    Deferring '%s' to run once as '%s'\n", b.Bin, b.Coalesce)
+	b.log = nil
+	t.deferred = append(t.deferred, deferredBin{bin: b, env: env})
+}
+
+// runCoalesced runs the first of the bins deferred under each Coalesce identifier a single time,
+// generating a result for each which lists the triggers that requested it. The outcome is passed
+// back to the pending outputs of every trigger which requested it.
+func runCoalesced(s Scope, results []Trigger) (coalesced []Trigger) {
+	var ids []string
+	first := make(map[string]deferredBin)
+	requested := make(map[string][]string)
+	for _, t := range results {
+		for _, d := range t.deferred {
+			id := d.bin.Coalesce
+			if _, ok := first[id]; !ok {
+				ids = append(ids, id)
+				first[id] = d
+			}
+			requested[id] = append(requested[id], t.Name)
+		}
+	}
+	for _, id := range ids {
+		d := first[id]
+		t := Trigger{
+			Name: fmt.Sprintf("%s (coalesced from %s)", id, strings.Join(requested[id], ", ")),
+		}
+		s.Events.Start(&t)
+		out := Output{Status: Skipped, Message: "the run being interrupted"}
+		if !s.interrupted() {
+			out = d.bin.Execute(s, d.env)
+		}
+		for i := range results {
+			results[i].completeCoalesced(id, out)
+		}
+		out.Name = d.bin.Task
+		t.Output = append(t.Output, out)
+		t.Finish(s)
+		coalesced = append(coalesced, t)
+	}
+	return
+}
+
+// completeCoalesced fills in the pending outputs of the bins deferred under a Coalesce identifier
+// with the outcome of running it
+func (t *Trigger) completeCoalesced(id string, result Output) {
+	for i := range t.Output {
+		out := &t.Output[i]
+		if out.coalesced != id {
+			continue
+		}
+		out.Status = result.Status
+		out.Message = result.Message
+		out.Usage = result.Usage
+		out.Duration = result.Duration
+		out.coalesced = ""
+	}
+}
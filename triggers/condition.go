@@ -0,0 +1,77 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Condition runs a single Bin only when a file contains a line matching a pattern, or only when it
+// does not. A missing file contains nothing, so it never matches.
+type Condition struct {
+	// File is the absolute path of the file to search
+	File string `toml:"file"`
+	// Matches is the regular expression to search for, where '^' and '$' match at every line
+	Matches string `toml:"matches"`
+	// Absent runs the bin when nothing matches, instead
+	Absent bool `toml:"absent"`
+
+	matches *regexp.Regexp
+}
+
+// Validate checks for errors in a Condition and compiles its pattern
+func (c *Condition) Validate() (err error) {
+	if c == nil {
+		return nil
+	}
+	if !filepath.IsAbs(c.File) {
+		return fmt.Errorf("condition file '%s' must be an absolute path", c.File)
+	}
+	// An empty pattern matches every file, even an empty or missing one
+	if len(c.Matches) == 0 {
+		return fmt.Errorf("condition for file '%s' must have a pattern to match", c.File)
+	}
+	if c.matches, err = regexp.Compile("(?m)" + c.Matches); err != nil {
+		return fmt.Errorf("invalid condition pattern '%s', reason: %s", c.Matches, err)
+	}
+	return nil
+}
+
+// Reason checks if the file satisfies the Condition, or explains why not. Failing to read the file
+// (other than it being missing) is an error, rather than a reason to skip.
+func (c *Condition) Reason() (reason string, ok bool, err error) {
+	if c == nil {
+		return "", true, nil
+	}
+	content, err := ioutil.ReadFile(c.File)
+	switch {
+	case os.IsNotExist(err):
+		err = nil
+	case err != nil:
+		return "", false, fmt.Errorf("failed to read condition file, reason: %s", err)
+	}
+	found := c.matches.Match(content)
+	switch {
+	case found && c.Absent:
+		return fmt.Sprintf("'%s' matching '%s'", c.File, c.Matches), false, nil
+	case !found && !c.Absent:
+		return fmt.Sprintf("'%s' not matching '%s'", c.File, c.Matches), false, nil
+	}
+	return "", true, nil
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConditionValidate(t *testing.T) {
+	tests := []struct {
+		condition Condition
+		valid     bool
+	}{
+		{Condition{File: "/etc/os-release", Matches: "^ID=solus$"}, true},
+		{Condition{File: "/etc/os-release", Matches: ""}, false},
+		{Condition{File: "/etc/os-release", Matches: "("}, false},
+		{Condition{File: "os-release", Matches: "^ID=solus$"}, false},
+	}
+	for _, test := range tests {
+		if err := test.condition.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid to be %t, got %v", test.condition, test.valid, err)
+		}
+	}
+}
+
+func TestConditionReason(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-condition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	release := filepath.Join(dir, "os-release")
+	if err = ioutil.WriteFile(release, []byte("NAME=Solus\nID=solus\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	tests := []struct {
+		condition Condition
+		ok        bool
+		reason    string
+	}{
+		{Condition{File: release, Matches: "^ID=solus$"}, true, ""},
+		{Condition{File: release, Matches: "^ID=fedora$"}, false, "'" + release + "' not matching '^ID=fedora$'"},
+		{Condition{File: release, Matches: "^ID=solus$", Absent: true}, false, "'" + release + "' matching '^ID=solus$'"},
+		{Condition{File: release, Matches: "^ID=fedora$", Absent: true}, true, ""},
+		// A missing file contains nothing
+		{Condition{File: missing, Matches: "^ID=solus$"}, false, "'" + missing + "' not matching '^ID=solus$'"},
+		{Condition{File: missing, Matches: "^ID=solus$", Absent: true}, true, ""},
+	}
+	for _, test := range tests {
+		if err := test.condition.Validate(); err != nil {
+			t.Fatalf("%+v: %s", test.condition, err)
+		}
+		reason, ok, err := test.condition.Reason()
+		if err != nil {
+			t.Errorf("%+v: unexpected error %s", test.condition, err)
+			continue
+		}
+		if ok != test.ok || reason != test.reason {
+			t.Errorf("%+v: expected %t '%s', got %t '%s'", test.condition, test.ok, test.reason, ok, reason)
+		}
+	}
+}
+
+func TestConditionReasonUnreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-condition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A directory cannot be read as a file, even by root
+	c := Condition{File: dir, Matches: "^ID=solus$", Absent: true}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := c.Reason(); err == nil || ok {
+		t.Errorf("expected an error for an unreadable file, got %t %v", ok, err)
+	}
+}
@@ -17,9 +17,12 @@ package triggers
 import (
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"github.com/DataDrake/waterlog/level"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Load reads a Trigger configuration from a file and parses it
@@ -35,6 +38,11 @@ func (t *Trigger) Load(path string) error {
 		return fmt.Errorf("unable to read config file located at %s", path)
 	}
 
+	return t.Parse(cfg, path)
+}
+
+// Parse reads a configuration which was read from path
+func (t *Trigger) Parse(cfg []byte, path string) error {
 	// Save the configuration into the content structure
 	if err := toml.Unmarshal(cfg, t); err != nil {
 		return fmt.Errorf("unable to read config file located at %s due to %s", path, err.Error())
@@ -42,12 +50,107 @@ func (t *Trigger) Load(path string) error {
 	return nil
 }
 
+// validatePaths rejects relative paths, which would depend on the working directory of usysconf.
+// Check, skip and exclude paths are used as written, so they must be absolute. Remove paths are
+// expanded first (see Trigger.Expand), so they may also start with "~/", a placeholder or a
+// variable, and are checked again once expanded.
+func (t *Trigger) validatePaths() error {
+	var literal []string
+	if t.Check != nil {
+		literal = append(literal, t.Check.Paths...)
+		literal = append(literal, t.Check.WatchPaths...)
+	}
+	if t.Skip != nil {
+		literal = append(literal, t.Skip.Paths...)
+	}
+	if t.RemoveDirs != nil {
+		literal = append(literal, t.RemoveDirs.Exclude...)
+		for _, path := range t.RemoveDirs.Paths {
+			if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "%") && !strings.HasPrefix(path, "$") {
+				literal = append(literal, path)
+			}
+		}
+	}
+	for _, path := range literal {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("path '%s' must be absolute", path)
+		}
+	}
+	return nil
+}
+
+// logLevels are the names accepted by Trigger.LogLevel
+var logLevels = map[string]uint8{
+	"error": level.Error,
+	"warn":  level.Warn,
+	"good":  level.Good,
+	"info":  level.Info,
+	"debug": level.Debug,
+}
+
 // Validate checks for errors in a Trigger configuration
 func (t *Trigger) Validate() error {
 	// Verify that there is at least one binary to execute, otherwise there
-	// is no need to continue
-	if len(t.Bins) == 0 {
+	// is no need to continue, unless it is a Native trigger
+	if len(t.Bins) == 0 && t.native == nil {
 		return fmt.Errorf("triggers must contain at least one [[bin]]")
 	}
+	if t.Check != nil {
+		if err := t.Check.Validate(); err != nil {
+			return err
+		}
+	}
+	if t.RemoveDirs != nil {
+		if err := t.RemoveDirs.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := t.validatePaths(); err != nil {
+		return err
+	}
+	if err := t.validateConditions(); err != nil {
+		return err
+	}
+	if t.CPU != nil {
+		if err := t.CPU.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(t.Timeout) > 0 {
+		var err error
+		if t.timeout, err = time.ParseDuration(t.Timeout); err != nil || t.timeout <= 0 {
+			return fmt.Errorf("invalid timeout '%s'", t.Timeout)
+		}
+	}
+	if t.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	if len(t.RetryDelay) > 0 {
+		var err error
+		if t.retryDelay, err = time.ParseDuration(t.RetryDelay); err != nil {
+			return fmt.Errorf("invalid retry delay '%s', reason: %s", t.RetryDelay, err)
+		}
+	}
+	if len(t.LogLevel) > 0 {
+		var ok bool
+		if t.logLevel, ok = logLevels[strings.ToLower(t.LogLevel)]; !ok {
+			return fmt.Errorf("invalid log level '%s', must be 'error', 'warn', 'info' or 'debug'", t.LogLevel)
+		}
+	}
+	switch t.Phase {
+	case "", PhaseBoot, PhaseDeferred:
+	default:
+		return fmt.Errorf("invalid phase '%s', must be '%s' or '%s'", t.Phase, PhaseBoot, PhaseDeferred)
+	}
+	if err := t.validateEnv(); err != nil {
+		return err
+	}
+	for i := range t.Bins {
+		if err := t.Bins[i].Validate(); err != nil {
+			return fmt.Errorf("invalid bin '%s', reason: %s", t.Bins[i].Task, err)
+		}
+	}
+	// Only warnings, see LintArgs
+	t.lint = t.lintArgs()
 	return nil
 }
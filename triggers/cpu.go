@@ -0,0 +1,61 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	log "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/util"
+)
+
+// cpuPeriod is the cgroup scheduling period, in microseconds, that quotas are relative to
+const cpuPeriod = 100000
+
+// CPU contains limits on the processor time available to the bins of a trigger. Limits are
+// applied with a cgroup when running as root, and ignored when cgroups are unavailable.
+type CPU struct {
+	// Quota is the percentage of a single CPU which may be used, i.e. 150 for one and a half CPUs
+	Quota int `toml:"quota"`
+	// CPUs restricts the bins to a set of CPUs, i.e. "0-1,3"
+	CPUs string `toml:"cpus"`
+}
+
+// Validate checks for errors in a CPU configuration
+func (c *CPU) Validate() error {
+	if c.Quota < 0 {
+		return fmt.Errorf("cpu quota must not be negative")
+	}
+	return nil
+}
+
+// Cgroup sets up a cgroup for a trigger with these limits, returning an empty path on failure
+func (c *CPU) Cgroup(name string) string {
+	settings := make(map[string]string)
+	if c.Quota > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", c.Quota*cpuPeriod/100, cpuPeriod)
+	}
+	if len(c.CPUs) > 0 {
+		settings["cpuset.cpus"] = c.CPUs
+	}
+	if len(settings) == 0 {
+		return ""
+	}
+	path, err := util.NewCgroup(name, settings)
+	if err != nil {
+		log.Warnf("CPU limits for '%s' not applied, reason: %s\n", name, err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// deviceOf finds the device holding a path, ok is false when it is unknown
+func deviceOf(path string) (dev uint64, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
+
+// executeDevices runs the invocations of a fanned-out Bin with one worker per device holding their
+// paths, so that invocations on the same device are run one at a time, in order. Invocations on an
+// unknown device share a single worker of their own. The output of each invocation to the log file is
+// buffered, and written out along with anything printed about it once it has finished, so that the
+// invocations running at the same time are not interleaved.
+func (t *Trigger) executeDevices(s Scope, env map[string]string, bins []Bin, outputs []Output, deadline time.Time) {
+	var queues [][]int
+	devices := make(map[uint64]int)
+	unknown := -1
+	for i, b := range bins {
+		// A batch is placed by its first path
+		dev, ok := deviceOf(strings.SplitN(b.fanOut, "\n", 2)[0])
+		if !ok {
+			if unknown < 0 {
+				unknown = len(queues)
+				queues = append(queues, nil)
+			}
+			queues[unknown] = append(queues[unknown], i)
+			continue
+		}
+		q, found := devices[dev]
+		if !found {
+			q = len(queues)
+			devices[dev] = q
+			queues = append(queues, nil)
+		}
+		queues[q] = append(queues[q], i)
+	}
+	var printLock sync.Mutex
+	var wg sync.WaitGroup
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue []int) {
+			defer wg.Done()
+			for _, i := range queue {
+				b := bins[i]
+				logFile := b.log
+				var buff bytes.Buffer
+				if logFile != nil {
+					b.log = &buff
+				}
+				t.executeBin(s, env, b, &outputs[i], deadline, &printLock)
+				if buff.Len() > 0 {
+					printLock.Lock()
+					_, _ = logFile.Write(buff.Bytes())
+					printLock.Unlock()
+				}
+			}
+		}(queue)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// parallelExecutor records the most Commands which were running at the same time
+type parallelExecutor struct {
+	lock         sync.Mutex
+	running, max int
+}
+
+// Run fulfills the Executor interface
+func (p *parallelExecutor) Run(ctx context.Context, c Command) (Result, error) {
+	p.lock.Lock()
+	if p.running++; p.running > p.max {
+		p.max = p.running
+	}
+	p.lock.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	p.lock.Lock()
+	p.running--
+	p.lock.Unlock()
+	return Result{}, nil
+}
+
+func TestExecuteDevicesUnknown(t *testing.T) {
+	var bins []Bin
+	var outputs []Output
+	for i := 0; i < 5; i++ {
+		b := Bin{Bin: "/bin/touch", Replace: &Replace{}}
+		b.fanOut = fmt.Sprintf("/nonexistent/usysconf/%d", i)
+		bins = append(bins, b)
+		outputs = append(outputs, Output{})
+	}
+	exec := &parallelExecutor{}
+	tr := Trigger{Name: "devices"}
+	tr.executeDevices(Scope{Executor: exec}, nil, bins, outputs, time.Time{})
+	if exec.max != 1 {
+		t.Errorf("expected the invocations on unknown devices to run one at a time, got %d at once", exec.max)
+	}
+	for i, out := range outputs {
+		if out.Status != Success {
+			t.Errorf("expected invocation %d to succeed, got %s", i, out.Status)
+		}
+	}
+}
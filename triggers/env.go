@@ -0,0 +1,203 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"github.com/getsolus/usysconf/util"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// osReleasePrefix marks an Env value to be looked up in os-release, i.e. "osrelease:VERSION_ID"
+const osReleasePrefix = "osrelease:"
+
+// envRef matches the "${VAR}" references within an Env value
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Environment resolves the Env of a trigger into the values passed to its bins. Missing
+// os-release keys expand to an empty string, or are an error for a Strict Scope.
+//
+// Values may reference other keys of the Env (i.e. "${PREFIX}/bin"), which are resolved first, or
+// the inherited environment. A value referencing its own key (i.e. "/opt/foo/bin:${PATH}") gets
+// the inherited value. Keys which reference each other in a cycle are an error. The variables of
+// the EnvFile are passed beneath the Env, which may also reference them, and above the inherited
+// environment, which is then passed along too. A BinPath overrides any PATH.
+func (t *Trigger) Environment(s Scope) (env map[string]string, err error) {
+	env, _, err = t.environment(s)
+	return
+}
+
+const (
+	// OriginInherited variables come from the environment of usysconf itself
+	OriginInherited = "inherited"
+	// OriginEnv variables come from the Env of the trigger
+	OriginEnv = "env"
+	// OriginEnvFile variables come from the EnvFile of the trigger
+	OriginEnvFile = "env-file"
+	// OriginOSRelease variables come from os-release, through the Env of the trigger
+	OriginOSRelease = "os-release"
+	// OriginPath is the PATH set from the BinPath of the trigger
+	OriginPath = "path"
+	// OriginFanOut is the variable holding the current path of a fan-out, see Replace.Env
+	OriginFanOut = "fan-out"
+)
+
+// EnvVar is a single variable passed to a bin, along with the layer its value came from
+type EnvVar struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+// environment resolves the Env of a trigger, noting the origin of each of the variables
+func (t *Trigger) environment(s Scope) (env, origins map[string]string, err error) {
+	file, err := t.readEnvFile()
+	if err != nil {
+		return
+	}
+	return t.resolveAll(s, file)
+}
+
+// readEnvFile reads the variables of the EnvFile, if any
+func (t *Trigger) readEnvFile() (map[string]string, error) {
+	if len(t.EnvFile) == 0 {
+		return nil, nil
+	}
+	path := strings.TrimPrefix(t.EnvFile, "-")
+	file, err := util.ReadEnvFile(path)
+	if os.IsNotExist(err) && path != t.EnvFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file, reason: %s", err)
+	}
+	return file, nil
+}
+
+// resolveAll resolves the Env of a trigger on top of the variables from its EnvFile, and the
+// inherited environment beneath those when there is an EnvFile
+func (t *Trigger) resolveAll(s Scope, file map[string]string) (env, origins map[string]string, err error) {
+	if len(t.Env) == 0 && len(file) == 0 && len(t.BinPath) == 0 {
+		return
+	}
+	env = make(map[string]string)
+	origins = make(map[string]string)
+	for k, v := range t.Env {
+		if _, err = t.resolveEnv(k, s, file, env, make(map[string]bool)); err != nil {
+			return
+		}
+		origins[k] = OriginEnv
+		if strings.HasPrefix(v, osReleasePrefix) {
+			origins[k] = OriginOSRelease
+		}
+	}
+	for k, v := range file {
+		if _, ok := env[k]; !ok {
+			env[k] = v
+			origins[k] = OriginEnvFile
+		}
+	}
+	// Like a systemd EnvironmentFile, the file adds to the inherited environment rather than replacing it
+	if len(t.EnvFile) > 0 {
+		for _, kv := range os.Environ() {
+			pieces := strings.SplitN(kv, "=", 2)
+			if _, ok := env[pieces[0]]; !ok && len(pieces) == 2 {
+				env[pieces[0]] = pieces[1]
+			}
+		}
+	}
+	if len(t.BinPath) > 0 {
+		env["PATH"] = t.searchPath()
+		origins["PATH"] = OriginPath
+	}
+	return
+}
+
+// ExplainEnv gets every variable a Bin will receive, in the order they are passed to it, along with
+// the origin of its value
+func (t *Trigger) ExplainEnv(s Scope, b Bin) (vars []EnvVar, err error) {
+	env, origins, err := t.environment(s)
+	if err != nil {
+		return
+	}
+	pairs := b.environment(env)
+	if pairs == nil {
+		pairs = os.Environ()
+	}
+	for i, kv := range pairs {
+		pieces := strings.SplitN(kv, "=", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		origin, ok := origins[pieces[0]]
+		switch {
+		case len(b.fanOut) > 0 && i == len(pairs)-1:
+			origin = OriginFanOut
+		case !ok:
+			origin = OriginInherited
+		}
+		vars = append(vars, EnvVar{Key: pieces[0], Value: pieces[1], Origin: origin})
+	}
+	return
+}
+
+// resolveEnv resolves a single key of the Env, after any keys it references. Keys missing from the
+// Env are looked up in the variables of the EnvFile, then the inherited environment.
+func (t *Trigger) resolveEnv(k string, s Scope, file, env map[string]string, stack map[string]bool) (v string, err error) {
+	if v, ok := env[k]; ok {
+		return v, nil
+	}
+	if stack[k] {
+		return "", fmt.Errorf("env '%s' references itself through other keys", k)
+	}
+	stack[k] = true
+	defer delete(stack, k)
+	v = t.Env[k]
+	if strings.HasPrefix(v, osReleasePrefix) {
+		key := strings.TrimPrefix(v, osReleasePrefix)
+		var ok bool
+		if v, ok = util.OSRelease(key); !ok && s.Strict {
+			return "", fmt.Errorf("os-release key '%s' not found for '%s'", key, k)
+		}
+		env[k] = v
+		return
+	}
+	v = envRef.ReplaceAllStringFunc(v, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		if _, ok := t.Env[name]; ok && name != k && err == nil {
+			var value string
+			value, err = t.resolveEnv(name, s, file, env, stack)
+			return value
+		}
+		if value, ok := file[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
+	if err != nil {
+		return "", err
+	}
+	env[k] = v
+	return
+}
+
+// validateEnv rejects an Env with keys which reference each other in a cycle. The EnvFile is only
+// read when the bins are run, since it may not exist yet.
+func (t *Trigger) validateEnv() error {
+	_, _, err := t.resolveAll(Scope{}, nil)
+	return err
+}
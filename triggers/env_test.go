@@ -0,0 +1,113 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"github.com/getsolus/usysconf/util"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironmentEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "defaults")
+	if err = ioutil.WriteFile(file, []byte("PREFIX=/opt/foo\nHOME=/var/lib/foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("USYSCONF_TEST_INHERITED", "kept")
+	defer os.Unsetenv("USYSCONF_TEST_INHERITED")
+	tr := Trigger{
+		Name:    "env",
+		Env:     map[string]string{"BIN": "${PREFIX}/bin"},
+		EnvFile: file,
+	}
+	env, err := tr.Environment(Scope{})
+	if err != nil {
+		t.Fatalf("Environment: %s", err)
+	}
+	expected := map[string]string{
+		"BIN":                     "/opt/foo/bin",
+		"PREFIX":                  "/opt/foo",
+		"HOME":                    "/var/lib/foo",
+		"PATH":                    os.Getenv("PATH"),
+		"USYSCONF_TEST_INHERITED": "kept",
+	}
+	for k, v := range expected {
+		if env[k] != v {
+			t.Errorf("expected %s to be '%s', got '%s'", k, v, env[k])
+		}
+	}
+	// The Env on its own still replaces the inherited environment
+	tr.EnvFile = ""
+	tr.Env = map[string]string{"BIN": "/opt/foo/bin"}
+	if env, err = tr.Environment(Scope{}); err != nil {
+		t.Fatalf("Environment: %s", err)
+	}
+	if _, ok := env["USYSCONF_TEST_INHERITED"]; ok || len(env) != 1 {
+		t.Errorf("expected only the Env without an env file, got %v", env)
+	}
+}
+
+func TestEnvironmentMissingEnvFile(t *testing.T) {
+	os.Setenv("USYSCONF_TEST_INHERITED", "kept")
+	defer os.Unsetenv("USYSCONF_TEST_INHERITED")
+	tr := Trigger{Name: "env", EnvFile: "-/nonexistent/usysconf/defaults"}
+	env, err := tr.Environment(Scope{})
+	if err != nil {
+		t.Fatalf("expected a missing optional env file to be ignored, got %s", err)
+	}
+	// Nothing to pass, so the bins inherit everything
+	if env != nil && env["USYSCONF_TEST_INHERITED"] != "kept" {
+		t.Errorf("expected the inherited environment, got %v", env)
+	}
+	tr.EnvFile = "/nonexistent/usysconf/defaults"
+	if _, err = tr.Environment(Scope{}); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
+
+func TestEnvironmentOSRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usysconf-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) {
+		util.OSReleasePath = path
+		util.ResetOSRelease()
+	}(util.OSReleasePath)
+	util.OSReleasePath = filepath.Join(dir, "os-release")
+	if err = ioutil.WriteFile(util.OSReleasePath, []byte("VERSION_ID=4.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	util.ResetOSRelease()
+	tr := Trigger{Name: "env", Env: map[string]string{"VERSION": "osrelease:VERSION_ID", "CODENAME": "osrelease:VERSION_CODENAME"}}
+	env, err := tr.Environment(Scope{})
+	if err != nil {
+		t.Fatalf("Environment: %s", err)
+	}
+	if env["VERSION"] != "4.1" || env["CODENAME"] != "" {
+		t.Errorf("expected VERSION=4.1 and an empty CODENAME, got %v", env)
+	}
+	if _, err = tr.Environment(Scope{Strict: true}); err == nil {
+		t.Error("expected a missing os-release key to be an error when strict")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"encoding/json"
+	log "github.com/DataDrake/waterlog"
+	"net"
+	"time"
+)
+
+// Event is sent for every trigger as it starts and finishes, using the same fields as a TriggerSummary
+type Event struct {
+	Event  string    `json:"event"`
+	Time   time.Time `json:"time"`
+	Name   string    `json:"name"`
+	Status *Status   `json:"status,omitempty"`
+}
+
+// Events streams the progress of a run to a Unix datagram socket
+type Events struct {
+	conn net.Conn
+}
+
+// DialEvents connects to the Unix datagram socket at path
+func DialEvents(path string) (*Events, error) {
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Events{conn: conn}, nil
+}
+
+// Close disconnects from the socket
+func (e *Events) Close() error {
+	if e == nil {
+		return nil
+	}
+	return e.conn.Close()
+}
+
+// Start sends an event for a trigger which is about to run
+func (e *Events) Start(t *Trigger) {
+	e.send(Event{Event: "start", Name: t.Name})
+}
+
+// Finish sends an event with the final status of a trigger
+func (e *Events) Finish(t *Trigger) {
+	status := t.Status()
+	e.send(Event{Event: "finish", Name: t.Name, Status: &status})
+}
+
+// send writes out a single event, a failure is logged rather than ending the run
+func (e *Events) send(ev Event) {
+	if e == nil {
+		return
+	}
+	ev.Time = time.Now().UTC()
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		log.Warnf("Failed to encode event, reason: %s\n", err)
+		return
+	}
+	if _, err = e.conn.Write(raw); err != nil {
+		log.Warnf("Failed to send event, reason: %s\n", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"context"
+	log "github.com/DataDrake/waterlog"
+	"github.com/getsolus/usysconf/util"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// Command describes a single process to be run by an Executor
+type Command struct {
+	Bin    string
+	Args   []string
+	Env    []string
+	Cgroup string
+	// Dir is the working directory of the process, the current directory when empty
+	Dir string
+	// Log receives a copy of the stdout and stderr, when set
+	Log io.Writer
+	// Umask replaces the file mode creation mask of the process, when set
+	Umask *int
+	// OOMScoreAdj replaces the OOM score adjustment of the process once it has started, when not 0
+	OOMScoreAdj int
+	// PIDNamespace runs the process in a new PID namespace, when possible
+	PIDNamespace bool
+	// CaptureStderr fills in Result.Stderr, at the cost of the ordering between stdout and stderr
+	// in the combined output
+	CaptureStderr bool
+}
+
+// Result contains the details of a completed Command
+type Result struct {
+	// Output is the combined stdout and stderr
+	Output []byte
+	// Stderr is a separate copy of the stderr, when requested by Command.CaptureStderr
+	Stderr []byte
+	Usage  Usage
+}
+
+// Executor runs the processes for Bins, so that they can be faked or intercepted by embedders
+type Executor interface {
+	// Run executes a Command to completion
+	Run(ctx context.Context, c Command) (Result, error)
+}
+
+// ExecExecutor is the default Executor, backed by os/exec
+type ExecExecutor struct{}
+
+// Run executes a Command as a child process
+func (ExecExecutor) Run(ctx context.Context, c Command) (res Result, err error) {
+	var buff, errBuff bytes.Buffer
+	namespaced := c.PIDNamespace
+	if namespaced && os.Geteuid() != 0 {
+		log.Debugf("    Running '%s' without a PID namespace, root privileges are required\n", c.Bin)
+		namespaced = false
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	cmd := c.command(&buff, &errBuff, namespaced)
+	err = start(cmd, c.Umask)
+	if err != nil && namespaced {
+		log.Debugf("    Running '%s' without a PID namespace, reason: %s\n", c.Bin, err)
+		buff.Reset()
+		errBuff.Reset()
+		cmd = c.command(&buff, &errBuff, false)
+		err = start(cmd, c.Umask)
+	}
+	if err != nil {
+		res.Output = buff.Bytes()
+		res.Stderr = errBuff.Bytes()
+		return
+	}
+	if len(c.Cgroup) > 0 {
+		if err := util.JoinCgroup(c.Cgroup, cmd.Process.Pid); err != nil {
+			log.Warnf("    Failed to apply CPU limits to '%s', reason: %s\n", c.Bin, err)
+		}
+	}
+	if c.OOMScoreAdj != 0 {
+		if err := util.SetOOMScoreAdj(cmd.Process.Pid, c.OOMScoreAdj); err != nil {
+			log.Warnf("    Failed to adjust the OOM score of '%s', reason: %s\n", c.Bin, err)
+		}
+	}
+	stop := killGroup(ctx, cmd.Process.Pid)
+	err = cmd.Wait()
+	stop()
+	res.Output = buff.Bytes()
+	res.Stderr = errBuff.Bytes()
+	res.Usage = usageOf(cmd.ProcessState)
+	return
+}
+
+// command creates the process for a Command, with the combined output sent to buff and a copy of
+// the stderr sent to errBuff, if requested. The process leads a new process group, so that it can be
+// killed along with anything it spawns, see killGroup.
+func (c Command) command(buff, errBuff *bytes.Buffer, namespaced bool) *exec.Cmd {
+	cmd := exec.Command(c.Bin, c.Args...)
+	cmd.Env = c.Env
+	cmd.Dir = c.Dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Add buffer for output
+	cmd.Stdout = buff
+	if c.Log != nil {
+		cmd.Stdout = io.MultiWriter(buff, c.Log)
+	}
+	cmd.Stderr = cmd.Stdout
+	if c.CaptureStderr {
+		// stdout and stderr are now written to from separate goroutines
+		combined := &lockedWriter{w: cmd.Stdout}
+		cmd.Stdout = combined
+		cmd.Stderr = io.MultiWriter(combined, errBuff)
+	}
+	if namespaced {
+		newPIDNamespace(cmd)
+	}
+	return cmd
+}
+
+// killGroup kills the whole process group led by pid once the Context is done (i.e. on a timeout),
+// so that no helpers are left running, nor holding on to the output of the process. The returned
+// function stops waiting, once the process has been waited for.
+func killGroup(ctx context.Context, pid int) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// The group is already gone when every process in it has exited
+			if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+				log.Warnf("    Failed to kill the process group of %d, reason: %s\n", pid, err)
+			}
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// startLock serializes starting processes, since the umask is shared by the whole process
+var startLock sync.Mutex
+
+// start runs a process, the umask is inherited so it is swapped in just for the start. Every start
+// holds startLock, so that no other process is started with the swapped umask, nor is the previous
+// umask lost to a concurrent swap.
+func start(cmd *exec.Cmd, umask *int) error {
+	startLock.Lock()
+	defer startLock.Unlock()
+	if umask == nil {
+		return cmd.Start()
+	}
+	prev := syscall.Umask(*umask)
+	defer syscall.Umask(prev)
+	return cmd.Start()
+}
+
+// lockedWriter serializes the writes to a Writer shared by stdout and stderr
+type lockedWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// Write fulfills the io.Writer interface
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.w.Write(p)
+}
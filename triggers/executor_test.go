@@ -0,0 +1,57 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// running checks if a process exists and has not exited, since an orphan may be left a zombie
+// when nothing reaps it
+func running(pid int) bool {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z" && fields[0] != "X"
+}
+
+func TestKillGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	// The child prints the pid of a sleeping grandchild, then waits on it
+	res, err := ExecExecutor{}.Run(ctx, Command{Bin: "/bin/sh", Args: []string{"-c", "sleep 60 & echo $!; wait"}})
+	if err == nil {
+		t.Fatal("expected the child to be killed on the timeout")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(res.Output)))
+	if err != nil {
+		t.Fatalf("expected the pid of the grandchild, got %q", res.Output)
+	}
+	for i := 0; running(pid) && i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running(pid) {
+		t.Errorf("expected the grandchild %d to be killed along with the child", pid)
+	}
+}
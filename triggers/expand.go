@@ -0,0 +1,78 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// placeholders generates the values for the "%name%" style placeholders of a trigger:
+//
+//	%name%  the name of the trigger
+//	%uid%   the user ID running usysconf
+//	%ncpu%  the number of CPUs, i.e. for "-j%ncpu%"
+func (t *Trigger) placeholders() map[string]string {
+	return map[string]string{
+		"%name%": t.Name,
+		"%uid%":  strconv.Itoa(os.Getuid()),
+		"%ncpu%": strconv.Itoa(runtime.NumCPU()),
+	}
+}
+
+// FillPlaceholders replaces the placeholders in the arguments of a bin, leaving any variables for
+// the bin itself to expand. This applies to every bin, so arguments which contained a placeholder
+// literally before they were introduced are changed too, see the README.
+func (t *Trigger) FillPlaceholders(args []string) []string {
+	filled := make([]string, 0, len(args))
+	for _, arg := range args {
+		for k, v := range t.placeholders() {
+			arg = strings.ReplaceAll(arg, k, v)
+		}
+		filled = append(filled, arg)
+	}
+	return filled
+}
+
+// Expand replaces the placeholders (i.e. "%name%") and variables (i.e. "${HOME}") in a string.
+// Variables come from the trigger Env, falling back to the process environment. If any variable
+// could not be found, ok will be false. A leading "~" is replaced by "${HOME}".
+func (t *Trigger) Expand(in string) (out string, ok bool) {
+	ok = true
+	out = in
+	if out == "~" || strings.HasPrefix(out, "~/") {
+		out = "${HOME}" + strings.TrimPrefix(out, "~")
+	}
+	for k, v := range t.placeholders() {
+		out = strings.ReplaceAll(out, k, v)
+	}
+	env, _ := t.Environment(Scope{})
+	out = os.Expand(out, func(key string) string {
+		if v, found := env[key]; found {
+			return v
+		}
+		if key == "UID" {
+			return strconv.Itoa(os.Getuid())
+		}
+		v, found := os.LookupEnv(key)
+		if !found {
+			ok = false
+		}
+		return v
+	})
+	return
+}
@@ -0,0 +1,89 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Gating describes the declared conditions for running a trigger, without evaluating them
+func (t *Trigger) Gating() (lines []string) {
+	if c := t.Check; c != nil {
+		if len(c.Paths) > 0 {
+			lines = append(lines, "check paths: "+strings.Join(c.Paths, ", "))
+		}
+		if c.Attempts > 0 {
+			lines = append(lines, fmt.Sprintf("check retries: %d, every %s", c.Attempts, c.delay))
+		}
+		if e := c.Exec; e != nil {
+			codes := e.Codes
+			if len(codes) == 0 {
+				codes = []int{0}
+			}
+			lines = append(lines, fmt.Sprintf("check exec: %s %s, exiting with %v within %s",
+				e.Bin, strings.Join(e.Args, " "), codes, e.timeout))
+		}
+		if v := c.Version; v != nil {
+			lines = append(lines, fmt.Sprintf("check version: %s %s, satisfying '%s'",
+				v.Bin, strings.Join(v.Args, " "), v.Constraint))
+		}
+		if len(c.Modules) > 0 {
+			lines = append(lines, "check modules: "+strings.Join(c.Modules, ", "))
+		}
+	} else {
+		lines = append(lines, "check: none, always skipped")
+	}
+	if s := t.Skip; s != nil {
+		var when []string
+		if s.Chroot {
+			when = append(when, "in a chroot")
+		}
+		if s.Live {
+			when = append(when, "in a live session")
+		}
+		if s.OnBattery {
+			when = append(when, "on battery")
+		}
+		if s.MinCPUs > 0 {
+			when = append(when, fmt.Sprintf("with fewer than %d CPUs", s.MinCPUs))
+		}
+		if len(s.Paths) > 0 {
+			when = append(when, "when found: "+strings.Join(s.Paths, ", "))
+		}
+		if len(when) > 0 {
+			lines = append(lines, "skip: "+strings.Join(when, "; "))
+		}
+	}
+	if o := t.Only; o != nil {
+		var when []string
+		if o.Chroot {
+			when = append(when, "in a chroot")
+		}
+		if o.Live {
+			when = append(when, "in a live session")
+		}
+		if len(o.Desktops) > 0 {
+			when = append(when, "under desktops: "+strings.Join(o.Desktops, ", "))
+		}
+		if len(when) > 0 {
+			lines = append(lines, "only: "+strings.Join(when, "; "))
+		}
+	}
+	if len(t.RunAfterChanged) > 0 {
+		lines = append(lines, "run after changed: "+strings.Join(t.RunAfterChanged, ", "))
+	}
+	return
+}
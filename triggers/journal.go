@@ -0,0 +1,131 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// JournalSocket is the socket of the native protocol of the systemd journal
+var JournalSocket = "/run/systemd/journal/socket"
+
+// journalField is a single field of a journal entry, in the order they are sent
+type journalField struct {
+	key, value string
+}
+
+// journalEntry gets the structured fields describing the result of a trigger
+func journalEntry(t Trigger) []journalField {
+	status := t.Status()
+	priority := "6"
+	switch status {
+	case Skipped:
+		priority = "7"
+	case Failure, Partial, CheckError:
+		priority = "3"
+	}
+	message := fmt.Sprintf("%s: %s", t.Name, status)
+	for _, out := range t.Output {
+		if (out.Status == Failure || out.Status == CheckError) && len(out.Message) > 0 {
+			message += ", " + out.Message
+			break
+		}
+	}
+	return []journalField{
+		{"MESSAGE", message},
+		{"PRIORITY", priority},
+		{"SYSLOG_IDENTIFIER", "usysconf"},
+		{"USYSCONF_TRIGGER", t.Name},
+		{"USYSCONF_STATUS", status.String()},
+	}
+}
+
+// encodeJournal renders the fields in the native protocol, values spanning several lines are prefixed by
+// their length instead of following a '='
+func encodeJournal(fields []journalField) []byte {
+	var buff bytes.Buffer
+	for _, f := range fields {
+		if !strings.Contains(f.value, "\n") {
+			fmt.Fprintf(&buff, "%s=%s\n", f.key, f.value)
+			continue
+		}
+		buff.WriteString(f.key + "\n")
+		binary.Write(&buff, binary.LittleEndian, uint64(len(f.value)))
+		buff.WriteString(f.value + "\n")
+	}
+	return buff.Bytes()
+}
+
+// journalTruncate is the most of a value sent again to the journal after its entry was too large
+// for a single datagram
+const journalTruncate = 48 * 1024
+
+// truncateJournal shortens every value of an entry to journalTruncate bytes
+func truncateJournal(fields []journalField) []journalField {
+	short := make([]journalField, len(fields))
+	for i, f := range fields {
+		short[i] = f
+		if len(f.value) > journalTruncate {
+			short[i].value = strings.ToValidUTF8(f.value[:journalTruncate], "") + "..."
+		}
+	}
+	return short
+}
+
+// textJournal renders the fields as plain "KEY=VALUE" lines, with the line breaks within values
+// escaped as "\\n"
+func textJournal(fields []journalField) []byte {
+	var buff bytes.Buffer
+	for _, f := range fields {
+		fmt.Fprintf(&buff, "%s=%s\n", f.key, strings.ReplaceAll(f.value, "\n", `\n`))
+	}
+	return buff.Bytes()
+}
+
+// WriteJournal sends an entry with the result of every trigger to the systemd journal. When the
+// journal is unavailable the entries are written to fallback instead, as "KEY=VALUE" lines separated
+// by blank lines. Entries too large for the journal are sent again with their values truncated, and
+// the first error is returned once every entry has been tried.
+func WriteJournal(fallback io.Writer, results []Trigger) error {
+	conn, err := net.Dial("unixgram", JournalSocket)
+	if err != nil {
+		for _, t := range results {
+			if _, err = fallback.Write(append(textJournal(journalEntry(t)), '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer conn.Close()
+	var first error
+	for _, t := range results {
+		fields := journalEntry(t)
+		_, err = conn.Write(encodeJournal(fields))
+		if errors.Is(err, syscall.EMSGSIZE) {
+			_, err = conn.Write(encodeJournal(truncateJournal(fields)))
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("failed to send the entry of trigger '%s', reason: %s", t.Name, err)
+		}
+	}
+	return first
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJournal listens on a temporary JournalSocket, returning the listener and a function to clean up
+func testJournal(t *testing.T) (*net.UnixConn, func()) {
+	dir, err := ioutil.TempDir("", "usysconf-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := JournalSocket
+	JournalSocket = filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: JournalSocket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, func() {
+		conn.Close()
+		JournalSocket = prev
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWriteJournalFallback(t *testing.T) {
+	prev := JournalSocket
+	JournalSocket = filepath.Join(os.TempDir(), "usysconf-missing-socket")
+	defer func() { JournalSocket = prev }()
+	var buff bytes.Buffer
+	results := []Trigger{{
+		Name:   "broken",
+		Output: []Output{{Status: Failure, Message: "first\nsecond"}},
+	}}
+	if err := WriteJournal(&buff, results); err != nil {
+		t.Fatalf("WriteJournal: %s", err)
+	}
+	expected := "MESSAGE=broken: failure, first\\nsecond\nPRIORITY=3\nSYSLOG_IDENTIFIER=usysconf\n" +
+		"USYSCONF_TRIGGER=broken\nUSYSCONF_STATUS=failure\n\n"
+	if buff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buff.String())
+	}
+}
+
+func TestWriteJournalOversized(t *testing.T) {
+	conn, cleanup := testJournal(t)
+	defer cleanup()
+	results := []Trigger{
+		{Name: "huge", Output: []Output{{Status: Failure, Message: strings.Repeat("x", 4*1024*1024)}}},
+		{Name: "small", Output: []Output{{Status: Success}}},
+	}
+	if err := WriteJournal(ioutil.Discard, results); err != nil {
+		t.Fatalf("WriteJournal: %s", err)
+	}
+	buff := make([]byte, 1024*1024)
+	for _, name := range []string{"huge", "small"} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buff)
+		if err != nil {
+			t.Fatalf("expected an entry for '%s', reason: %s", name, err)
+		}
+		if !bytes.Contains(buff[:n], []byte("USYSCONF_TRIGGER="+name+"\n")) {
+			t.Errorf("expected the entry of '%s', got %d bytes", name, n)
+		}
+		if n > journalTruncate+1024 {
+			t.Errorf("expected the entry of '%s' to be truncated, got %d bytes", name, n)
+		}
+	}
+}
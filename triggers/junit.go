@@ -0,0 +1,107 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// messages collects the messages of all outputs with any of the given statuses
+func (t *Trigger) messages(statuses ...Status) string {
+	var msgs []string
+	for _, out := range t.Output {
+		if !hasStatus(out.Status, statuses) || len(out.Message) == 0 {
+			continue
+		}
+		if len(out.SubTask) > 0 {
+			msgs = append(msgs, fmt.Sprintf("%s (%s): %s", out.Name, out.SubTask, out.Message))
+		} else {
+			msgs = append(msgs, out.Message)
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// hasStatus checks if a status is one of several
+func hasStatus(status Status, statuses []Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJUnit renders the results of a run as a JUnit XML document, with one testcase per trigger
+func WriteJUnit(w io.Writer, results []Trigger) error {
+	suite := junitSuite{
+		Name:  "usysconf",
+		Tests: len(results),
+	}
+	for _, t := range results {
+		c := junitCase{
+			Name:      t.Name,
+			ClassName: "usysconf",
+		}
+		switch t.Status() {
+		case Failure, Partial, CheckError:
+			c.Failure = &junitMessage{
+				Message: "trigger failed",
+				Text:    t.messages(Failure, CheckError),
+			}
+			suite.Failures++
+		case Skipped:
+			c.Skipped = &junitMessage{
+				Message: t.messages(Skipped),
+			}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
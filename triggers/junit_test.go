@@ -0,0 +1,42 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnitFailures(t *testing.T) {
+	results := []Trigger{
+		{Name: "broken", Output: []Output{{Status: Failure, Message: "exit status 1"}}},
+		{Name: "unresolved", Output: []Output{{Status: CheckError, Message: "permission denied"}}},
+		{Name: "fine", Output: []Output{{Status: Success}}},
+	}
+	var buff bytes.Buffer
+	if err := WriteJUnit(&buff, results); err != nil {
+		t.Fatalf("WriteJUnit: %s", err)
+	}
+	report := buff.String()
+	if !strings.Contains(report, `failures="2"`) {
+		t.Errorf("expected 2 failures, got:\n%s", report)
+	}
+	for _, reason := range []string{"exit status 1", "permission denied"} {
+		if !strings.Contains(report, reason) {
+			t.Errorf("expected the reason '%s', got:\n%s", reason, report)
+		}
+	}
+}
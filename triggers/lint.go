@@ -0,0 +1,71 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	log "github.com/DataDrake/waterlog"
+	"path/filepath"
+	"strings"
+)
+
+// shells interpret their arguments, so shell syntax is expected for them
+var shells = map[string]bool{
+	"ash": true, "bash": true, "dash": true, "sh": true, "zsh": true,
+}
+
+// shellSyntax is only meaningful to a shell, which bins are never run through
+var shellSyntax = []string{"|", "&&", ";", ">", "<", "$(", "`", "'", "\""}
+
+// lintArg guesses if an argument was written expecting a shell to interpret it
+func lintArg(arg string) (reason string, suspicious bool) {
+	for _, syntax := range shellSyntax {
+		if strings.Contains(arg, syntax) {
+			return "contains shell syntax '" + syntax + "', but bins are not run through a shell", true
+		}
+	}
+	if fields := strings.Fields(arg); strings.HasPrefix(arg, "-") && len(fields) > 1 && !strings.Contains(fields[0], "=") {
+		return "contains spaces, but is passed as a single argument", true
+	}
+	return "", false
+}
+
+// lintArgs finds the arguments of each Bin which look like they were meant for a shell
+func (t *Trigger) lintArgs() (findings []string) {
+	for _, b := range t.Bins {
+		if shells[filepath.Base(b.Bin)] {
+			continue
+		}
+		for _, arg := range b.Args {
+			if reason, ok := lintArg(arg); ok {
+				findings = append(findings, fmt.Sprintf("Argument '%s' of '%s' in '%s' %s", arg, b.Bin, t.Name, reason))
+			}
+		}
+	}
+	return
+}
+
+// LintArgs gets the arguments of each Bin which look like they were meant for a shell, as found
+// when the trigger was validated
+func (t *Trigger) LintArgs() []string {
+	return t.lint
+}
+
+// warnArgs warns about the arguments found by LintArgs
+func (t *Trigger) warnArgs() {
+	for _, finding := range t.lint {
+		log.Warnf("    %s\n", finding)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLintArgs(t *testing.T) {
+	tr := Trigger{
+		Name: "lint",
+		Bins: []Bin{
+			{Bin: "/usr/bin/update", Args: []string{"--force", "a | b", "--output /tmp/x", "--level=3 4"}},
+			{Bin: "/bin/sh", Args: []string{"-c", "a | b"}},
+		},
+	}
+	if err := tr.Validate(); err != nil {
+		t.Fatalf("expected suspicious arguments to only be warned about, got %s", err)
+	}
+	findings := tr.LintArgs()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %v", findings)
+	}
+	for i, arg := range []string{"'a | b'", "'--output /tmp/x'"} {
+		if !strings.Contains(findings[i], arg) || !strings.Contains(findings[i], "'/usr/bin/update'") {
+			t.Errorf("expected a finding for %s of '/usr/bin/update', got '%s'", arg, findings[i])
+		}
+	}
+}
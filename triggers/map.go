@@ -18,6 +18,8 @@ import (
 	"fmt"
 	log "github.com/DataDrake/waterlog"
 	"github.com/getsolus/usysconf/state"
+	"math/rand"
+	"os"
 	"sort"
 )
 
@@ -31,46 +33,197 @@ func Merge(left, right Map) {
 	}
 }
 
-// Print renders a Map in a human-readable format
-func Print(tm Map) {
-	var keys []string
-	max := 0
-	for k := range tm {
-		keys = append(keys, k)
-		if len(k) > max {
-			max = len(k)
+// PrintOptions selects the details included by Print
+type PrintOptions struct {
+	// Verbose includes the bins of each trigger
+	Verbose bool
+	// Gating includes the declared skip and check conditions of each trigger
+	Gating bool
+	// Plain renders each trigger as "name - description", rather than as a table
+	Plain bool
+	// MaxWidth is the widest a column of the table may be before it is truncated (default: 60)
+	MaxWidth int
+	// Tree groups the triggers by TreeTag or TreePhase, rather than rendering a table
+	Tree string
+}
+
+const (
+	// TreeTag groups the triggers by their Tags, falling back to TreePhase when none have any
+	TreeTag = "tag"
+	// TreePhase groups the triggers by their Phase
+	TreePhase = "phase"
+)
+
+// Print renders a Map in a human-readable format to stdout
+func Print(tm Map, opts PrintOptions) {
+	Fprint(os.Stdout, tm, opts)
+}
+
+// Shuffle randomizes the order of a list of trigger names, reproducibly for the same seed
+func Shuffle(names []string, seed int64) {
+	sort.Strings(names)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(names), func(i, j int) {
+		names[i], names[j] = names[j], names[i]
+	})
+}
+
+// order moves triggers after the triggers they RunAfterChanged, otherwise keeping the same order
+func order(tm Map, names []string) (ordered []string) {
+	selected := make(map[string]bool)
+	for _, name := range names {
+		selected[name] = true
+	}
+	placed := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if placed[name] {
+			return
+		}
+		placed[name] = true
+		for _, dep := range tm[name].RunAfterChanged {
+			if selected[dep] {
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return
+}
+
+// Unresolved finds the references between triggers, i.e. RunAfterChanged, naming triggers which do
+// not exist. A typo would otherwise leave the referencing trigger skipped on every run.
+func (tm Map) Unresolved() (errs []error) {
+	names := make([]string, 0, len(tm))
+	for name := range tm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := tm[name]
+		errs = append(errs, t.unresolved(tm)...)
+	}
+	return
+}
+
+// unresolved finds the references of a single trigger naming triggers which do not exist
+func (t *Trigger) unresolved(tm Map) (errs []error) {
+	for _, dep := range t.RunAfterChanged {
+		if _, ok := tm[dep]; !ok {
+			errs = append(errs, fmt.Errorf("trigger '%s' runs after changes from unknown trigger '%s'", t.Name, dep))
 		}
 	}
-	max += 4
-	sort.Strings(keys)
-	var t Trigger
-	f := fmt.Sprintf("%%%ds - %%s\n", max)
-	for _, key := range keys {
-		t = tm[key]
-		log.Printf(f, t.Name, t.Description)
+	return
+}
+
+// afterChanged checks if any of the triggers a trigger depends on did work
+func (t *Trigger) afterChanged(changed map[string]bool) bool {
+	if len(t.RunAfterChanged) == 0 {
+		return true
+	}
+	for _, name := range t.RunAfterChanged {
+		if changed[name] {
+			return true
+		}
 	}
-	log.Println()
+	t.Output = append(t.Output, Output{
+		Status:  Skipped,
+		Message: fmt.Sprintf("no changes from %v", t.RunAfterChanged),
+	})
+	return false
 }
 
-// Run executes a list of triggers, where available
-func Run(tm Map, s Scope, names []string) {
-	prev := state.Load()
+// Run executes a list of triggers, where available, returning the triggers that were found
+func Run(tm Map, s Scope, names []string) (results []Trigger) {
 	next := make(state.Map)
+	results = RunWithState(tm, s, names, state.Load(), next)
+	if !s.DryRun {
+		// Save new State for next run
+		if err := next.Save(); err != nil {
+			log.Errorf("Failed to save next state file, reason: %s\n", err)
+		}
+	}
+	return
+}
+
+// RunWithState executes a list of triggers like Run, against the prev state and recording the next
+// one, rather than the saved state, which is left alone. A trigger waiting for coalesced bins is only
+// recorded once they have run, and never counts as changed for RunAfterChanged.
+func RunWithState(tm Map, s Scope, names []string, prev, next state.Map) (results []Trigger) {
+	failures := 0
+	changed := make(map[string]bool)
 	// Iterate over triggers
-	for _, name := range names {
+	for _, name := range order(tm, names) {
 		// Get Trigger if available
 		t, ok := tm[name]
 		if !ok {
 			log.Warnf("Could not find trigger %s\n", name)
 			continue
 		}
-		// Run Trigger
+		// Skip the remaining triggers once the run has been interrupted
+		if s.interrupted() {
+			t.Output = append(t.Output, Output{
+				Status:  Skipped,
+				Message: "the run being interrupted",
+			})
+			t.Finish(s)
+			results = append(results, t)
+			continue
+		}
+		// Skip the remaining triggers once too many have failed
+		if !s.tolerates(failures) {
+			out := Output{
+				Status:  Skipped,
+				Message: fmt.Sprintf("reaching the maximum of %d failure(s)", s.MaxFailures),
+			}
+			if s.FailFast {
+				out.Message = "stopping at the first failure"
+			}
+			t.Output = append(t.Output, out)
+			t.Finish(s)
+			results = append(results, t)
+			continue
+		}
+		// Fail triggers referencing others which do not exist, which would otherwise never run
+		if errs := t.unresolved(tm); len(errs) > 0 {
+			t.Output = append(t.Output, Output{
+				Status:  Failure,
+				Message: errs[0].Error(),
+			})
+			t.Finish(s)
+			failures++
+			results = append(results, t)
+			continue
+		}
+		// Skip triggers waiting for changes from others
+		if !t.afterChanged(changed) {
+			t.Finish(s)
+			results = append(results, t)
+			continue
+		}
+		// Run Trigger, the outcome of a trigger waiting for coalesced bins is not known yet
 		t.Run(s, prev, next)
+		switch t.Status() {
+		case Success:
+			if len(t.deferred) == 0 {
+				changed[name] = true
+			}
+		case Failure, Partial, CheckError:
+			failures++
+		}
+		results = append(results, t)
 	}
-	if !s.DryRun {
-		// Save new State for next run
-		if err := next.Save(); err != nil {
-			log.Errorf("Failed to save next state file, reason: %s\n", err)
+	// Run the bins which were deferred to be coalesced, then record and stream the triggers waiting
+	// on them
+	results = append(results, runCoalesced(s, results)...)
+	for i := range results {
+		if len(results[i].deferred) > 0 {
+			results[i].record(s, prev, next)
+			s.Stream.Finish(&results[i])
 		}
 	}
+	return
 }
@@ -0,0 +1,148 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/getsolus/usysconf/state"
+	"strings"
+	"testing"
+)
+
+// coalescedTrigger creates a trigger running a bin, followed by a bin coalesced as "update"
+func coalescedTrigger(name, checked string) Trigger {
+	t := testTrigger(name, checked, "/bin/"+name)
+	t.Bins = append(t.Bins, Bin{Bin: "/bin/update", Coalesce: "update"})
+	return t
+}
+
+func TestRunWithStateCoalescedFailure(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	dependent := testTrigger("dependent", checked, "/bin/dependent")
+	dependent.RunAfterChanged = []string{"a"}
+	tm := Map{
+		"a":         coalescedTrigger("a", checked),
+		"b":         coalescedTrigger("b", checked),
+		"dependent": dependent,
+	}
+	exec := &fakeExecutor{fail: map[string]bool{"/bin/update": true}}
+	next := make(state.Map)
+	results := RunWithState(tm, Scope{Executor: exec}, []string{"a", "b", "dependent"}, make(state.Map), next)
+	updates := 0
+	for _, line := range exec.ran() {
+		if line == "/bin/update" {
+			updates++
+		}
+	}
+	if updates != 1 {
+		t.Errorf("expected the coalesced bin to run once, got %v", exec.ran())
+	}
+	statuses := make(map[string]Status)
+	for _, r := range results {
+		statuses[r.Name] = r.Status()
+	}
+	for _, name := range []string{"a", "b"} {
+		if statuses[name] != Partial {
+			t.Errorf("expected '%s' to be partial, got %s", name, statuses[name])
+		}
+		if _, ok := next[lastRunKey(name)]; ok {
+			t.Errorf("expected '%s' not to be recorded as run", name)
+		}
+	}
+	if statuses["dependent"] != Skipped {
+		t.Errorf("expected the dependent trigger to be skipped, got %s", statuses["dependent"])
+	}
+	if _, ok := next[checked]; ok {
+		t.Error("expected the checked path not to be recorded for the failed triggers")
+	}
+}
+
+func TestRunWithStateCoalescedSuccess(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	tm := Map{"a": coalescedTrigger("a", checked)}
+	next := make(state.Map)
+	results := RunWithState(tm, Scope{Executor: &fakeExecutor{}}, []string{"a"}, make(state.Map), next)
+	if status := results[0].Status(); status != Success {
+		t.Fatalf("expected 'a' to succeed, got %s", status)
+	}
+	if _, ok := next[lastRunKey("a")]; !ok {
+		t.Error("expected 'a' to be recorded as run")
+	}
+	if _, ok := next[checked]; !ok {
+		t.Error("expected the checked path to be recorded")
+	}
+}
+
+func TestRunWithStateUnresolved(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	typo := testTrigger("typo", checked, "/bin/typo")
+	typo.RunAfterChanged = []string{"missing"}
+	tm := Map{
+		"typo":  typo,
+		"other": testTrigger("other", checked, "/bin/other"),
+	}
+	if errs := tm.Unresolved(); len(errs) != 1 {
+		t.Fatalf("expected a single unresolved reference, got %v", errs)
+	}
+	exec := &fakeExecutor{}
+	results := RunWithState(tm, Scope{Executor: exec}, []string{"typo", "other"}, make(state.Map), make(state.Map))
+	for _, r := range results {
+		switch r.Name {
+		case "typo":
+			if r.Status() != Failure || !strings.Contains(r.Output[0].Message, "'missing'") {
+				t.Errorf("expected 'typo' to fail naming the unknown trigger, got %s: %v", r.Status(), r.Output)
+			}
+		case "other":
+			if r.Status() != Success {
+				t.Errorf("expected 'other' to still run, got %s", r.Status())
+			}
+		}
+	}
+	if strings.Join(exec.ran(), " ") != "/bin/other" {
+		t.Errorf("expected only 'other' to be run, got %v", exec.ran())
+	}
+}
+
+func TestRunWithStateCoalescedStream(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	tm := Map{"a": coalescedTrigger("a", checked)}
+	var buff bytes.Buffer
+	s := Scope{
+		Executor: &fakeExecutor{fail: map[string]bool{"/bin/update": true}},
+		Stream:   NewStream(&buff),
+	}
+	RunWithState(tm, s, []string{"a"}, make(state.Map), make(state.Map))
+	var statuses []string
+	for _, line := range strings.Split(strings.TrimSpace(buff.String()), "\n") {
+		var event struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("expected an event, got %q", line)
+		}
+		if event.Name == "a" {
+			statuses = append(statuses, event.Status)
+		}
+	}
+	if len(statuses) != 1 || statuses[0] != Partial.String() {
+		t.Errorf("expected a single partial result for 'a', got %v", statuses)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"path"
+	"strings"
+)
+
+// defaultMask matches variables which are always masked, in addition to Trigger.Mask
+var defaultMask = []string{"*PASSWORD*", "*SECRET*", "*TOKEN*"}
+
+// maskValue replaces the value of a masked variable
+const maskValue = "********"
+
+// masked checks if the value of a variable should be hidden from diagnostics
+func (t *Trigger) masked(key string) bool {
+	for _, patterns := range [][]string{defaultMask, t.Mask} {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, key); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaskText hides the values of any masked variables of the trigger Env wherever they appear in text
+func (t *Trigger) MaskText(text string) string {
+	env, _ := t.Environment(Scope{})
+	for k, v := range env {
+		if len(v) > 0 && t.masked(k) {
+			text = strings.ReplaceAll(text, v, maskValue)
+		}
+	}
+	return text
+}
+
+// MaskEnv hides the values of any masked variables in a list of KEY=VALUE pairs
+func (t *Trigger) MaskEnv(env []string) []string {
+	masked := make([]string, 0, len(env))
+	for _, kv := range env {
+		pieces := strings.SplitN(kv, "=", 2)
+		if len(pieces) == 2 && t.masked(pieces[0]) {
+			kv = pieces[0] + "=" + maskValue
+		}
+		masked = append(masked, kv)
+	}
+	return masked
+}
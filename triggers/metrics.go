@@ -0,0 +1,67 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// metricLabel escapes a label value for the Prometheus text exposition format
+var metricLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics renders the results of a run in the Prometheus text exposition format, i.e. for
+// the textfile collector of the node exporter
+func WriteMetrics(w io.Writer, results []Trigger) error {
+	metrics := []struct {
+		name, help, kind string
+		value            func(t Trigger) float64
+	}{
+		{"usysconf_trigger_duration_seconds", "Time taken by the bins of the trigger.", "gauge", func(t Trigger) float64 {
+			var total time.Duration
+			for _, out := range t.Output {
+				total += out.Duration
+			}
+			return total.Seconds()
+		}},
+		{"usysconf_trigger_failed", "Whether the trigger failed, even partially.", "gauge", func(t Trigger) float64 {
+			if status := t.Status(); status == Failure || status == Partial || status == CheckError {
+				return 1
+			}
+			return 0
+		}},
+		{"usysconf_trigger_skipped", "Whether the trigger was skipped.", "gauge", func(t Trigger) float64 {
+			if t.Status() == Skipped {
+				return 1
+			}
+			return 0
+		}},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+			return err
+		}
+		for _, t := range results {
+			if _, err := fmt.Fprintf(w, "%s{trigger=\"%s\"} %g\n", m.name, metricLabel.Replace(t.Name), m.value(t)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "# HELP usysconf_last_run_timestamp_seconds Time the run finished.\n"+
+		"# TYPE usysconf_last_run_timestamp_seconds gauge\nusysconf_last_run_timestamp_seconds %d\n", time.Now().Unix())
+	return err
+}
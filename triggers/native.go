@@ -0,0 +1,77 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Native is a trigger implemented in Go by an embedder, rather than by running bins. It is still
+// scheduled, skipped, checked and reported like any other trigger.
+type Native interface {
+	// Execute does the work of the trigger, once it has passed its Skip and Check. It is not called
+	// for a dry run. The TriggerName of each Output is filled in afterwards.
+	Execute(s Scope) []Output
+}
+
+var (
+	registered     = make(Map)
+	registeredLock sync.Mutex
+)
+
+// Register adds a Native trigger, which is loaded alongside the triggers read from files. Those
+// take precedence over it when they have the same name. The Description, Skip, Check and other
+// settings of base still apply, while its Bins are ignored. The settings are validated like those of
+// a file, and the trigger is not added when they are invalid. Like any other trigger, it only runs
+// when its Check.Paths have changed, so it must have some.
+func Register(name string, n Native, base Trigger) error {
+	base.Name = name
+	base.Bins = nil
+	base.native = n
+	if base.Check == nil || len(base.Check.Paths) == 0 {
+		return fmt.Errorf("invalid native trigger '%s', reason: it has no check paths, so it would always be skipped", name)
+	}
+	if err := base.Validate(); err != nil {
+		return fmt.Errorf("invalid native trigger '%s', reason: %s", name, err)
+	}
+	registeredLock.Lock()
+	defer registeredLock.Unlock()
+	registered[name] = base
+	return nil
+}
+
+// Registered gets a copy of every Native trigger added by Register
+func Registered() Map {
+	registeredLock.Lock()
+	defer registeredLock.Unlock()
+	tm := make(Map)
+	Merge(tm, registered)
+	return tm
+}
+
+// executeNative runs a Native trigger in place of its bins
+func (t *Trigger) executeNative(s Scope) {
+	if s.DryRun {
+		t.Output = append(t.Output, Output{Status: Success})
+		return
+	}
+	for _, out := range t.native.Execute(s) {
+		if len(out.TriggerName) == 0 {
+			out.TriggerName = t.Name
+		}
+		t.Output = append(t.Output, out)
+	}
+}
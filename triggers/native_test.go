@@ -0,0 +1,75 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"testing"
+	"time"
+)
+
+type nativeFunc func(s Scope) []Output
+
+func (f nativeFunc) Execute(s Scope) []Output {
+	return f(s)
+}
+
+func TestRegisterValidates(t *testing.T) {
+	n := nativeFunc(func(Scope) []Output { return []Output{{Status: Success}} })
+	base := Trigger{
+		Timeout: "1m",
+		Check:   &Check{Paths: []string{"/usr/share/fonts"}, Exec: &Exec{Bin: "/bin/true"}},
+	}
+	if err := Register("test-native", n, base); err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+	defer func() {
+		registeredLock.Lock()
+		delete(registered, "test-native")
+		registeredLock.Unlock()
+	}()
+	got, ok := Registered()["test-native"]
+	if !ok {
+		t.Fatal("expected the trigger to be registered")
+	}
+	if got.timeout != time.Minute {
+		t.Errorf("expected a timeout of 1m, got %s", got.timeout)
+	}
+	if got.Check.Exec.timeout != defaultExecTimeout {
+		t.Errorf("expected the default check exec timeout, got %s", got.Check.Exec.timeout)
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	n := nativeFunc(func(Scope) []Output { return nil })
+	check := &Check{Paths: []string{"/usr/share/fonts"}}
+	if err := Register("test-invalid", n, Trigger{RetryDelay: "soon", Check: check}); err == nil {
+		t.Fatal("expected an invalid retry delay to be rejected")
+	}
+	if _, ok := Registered()["test-invalid"]; ok {
+		t.Fatal("expected an invalid trigger not to be registered")
+	}
+}
+
+func TestRegisterWithoutPaths(t *testing.T) {
+	n := nativeFunc(func(Scope) []Output { return nil })
+	for _, base := range []Trigger{{}, {Check: &Check{Exec: &Exec{Bin: "/bin/true"}}}} {
+		if err := Register("test-no-paths", n, base); err == nil {
+			t.Errorf("expected a native trigger without check paths to be rejected, for %+v", base)
+		}
+	}
+	if _, ok := Registered()["test-no-paths"]; ok {
+		t.Fatal("expected a trigger without check paths not to be registered")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"os"
+	"strings"
+)
+
+// Only contains details for when something will exclusively be processed, based on the scope of
+// execution. Every condition which is set must be satisfied.
+type Only struct {
+	Chroot bool `toml:"chroot,omitempty"`
+	Live   bool `toml:"live,omitempty"`
+	// Desktops lists the desktop environments to run under, compared case-insensitively with any of
+	// the ':' separated names in XDG_CURRENT_DESKTOP, i.e. "GNOME"
+	Desktops []string `toml:"desktops"`
+}
+
+// Matches checks if the Scope satisfies all of the conditions, always true when there are none
+func (o *Only) Matches(s Scope) bool {
+	if o == nil {
+		return true
+	}
+	if o.Chroot && !s.Chroot {
+		return false
+	}
+	if o.Live && !s.Live {
+		return false
+	}
+	return len(o.Desktops) == 0 || o.onDesktop()
+}
+
+// onDesktop checks if any of the Desktops is current
+func (o *Only) onDesktop() bool {
+	for _, current := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		for _, desktop := range o.Desktops {
+			if len(current) > 0 && strings.EqualFold(current, desktop) {
+				return true
+			}
+		}
+	}
+	return false
+}
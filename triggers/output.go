@@ -14,11 +14,60 @@
 
 package triggers
 
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// DefaultTaskWidth is the historical alignment width for Task names
+const DefaultTaskWidth = 42
+
+// TaskWidth is the column width that Task names are padded (or truncated) to when printed
+var TaskWidth = DefaultTaskWidth
+
 // Output contains the details necessary to output the configuration details
 // to the user.
 type Output struct {
+	// TriggerName is the name of the Trigger which produced the output
+	TriggerName string
+	// Bin is the position of the Bin which produced the output, counting from 1, or 0 for outputs
+	// which did not come from a Bin
+	Bin int
+
 	Name    string
 	SubTask string
 	Message string
 	Status  Status
+	// Usage is only filled in for executed bins, on supported platforms
+	Usage Usage
+	// Duration is the time taken by an executed bin, including retries
+	Duration time.Duration
+
+	// output is the combined stdout and stderr of the last run of an executed bin
+	output []byte
+	// coalesced is the Coalesce identifier of a deferred bin, until it has run, see runCoalesced
+	coalesced string
+}
+
+// Label pads the Task name to TaskWidth, truncating it with an ellipsis if it is too long
+func (o Output) Label() string {
+	if TaskWidth <= 0 {
+		return o.Name
+	}
+	return pad(o.Name, TaskWidth)
+}
+
+// tail keeps the last n lines of the output of a bin, noting how many were left out, or all of them
+// when n is not positive
+func tail(output []byte, n int) []byte {
+	if n <= 0 {
+		return output
+	}
+	lines := bytes.Split(bytes.TrimRight(output, "\n"), []byte("\n"))
+	if len(lines) <= n {
+		return output
+	}
+	kept := bytes.Join(lines[len(lines)-n:], []byte("\n"))
+	return append([]byte(fmt.Sprintf("... %d more lines\n", len(lines)-n)), append(kept, '\n')...)
 }
@@ -0,0 +1,40 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"testing"
+)
+
+func TestTail(t *testing.T) {
+	for _, tc := range []struct {
+		output   string
+		n        int
+		expected string
+	}{
+		{"a\nb\nc\n", 0, "a\nb\nc\n"},
+		{"a\nb\nc\n", -1, "a\nb\nc\n"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\nc\n", 5, "a\nb\nc\n"},
+		{"a\nb\nc\n", 2, "... 1 more lines\nb\nc\n"},
+		{"a\nb\nc", 1, "... 2 more lines\nc\n"},
+		{"a\nb\nc\n\n\n", 1, "... 2 more lines\nc\n"},
+		{"", 1, ""},
+	} {
+		if actual := string(tail([]byte(tc.output), tc.n)); actual != tc.expected {
+			t.Errorf("expected the last %d lines of %q to be %q, got %q", tc.n, tc.output, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"path/filepath"
+)
+
+// TagAlways marks a trigger as affected by every package, for triggers with no associated files
+const TagAlways = "always"
+
+// HasTag checks if a trigger has been given a tag
+func (t *Trigger) HasTag(tag string) bool {
+	for _, other := range t.Tags {
+		if other == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns gets every path the trigger refers to: its check and watch paths, the Replace paths of
+// its bins and any absolute arguments
+func (t *Trigger) patterns() (patterns []string) {
+	if t.Check != nil {
+		patterns = append(patterns, t.Check.Paths...)
+		patterns = append(patterns, t.Check.WatchPaths...)
+	}
+	for _, b := range t.Bins {
+		if b.Replace != nil {
+			patterns = append(patterns, b.resolve(b.Replace.Paths)...)
+		}
+		for _, arg := range b.Args {
+			if filepath.IsAbs(arg) {
+				patterns = append(patterns, arg)
+			}
+		}
+	}
+	return
+}
+
+// Affected checks if any of the files (i.e. those installed by a package) are referred to by the
+// trigger, either directly or by being within a directory it refers to. Triggers tagged with
+// TagAlways are always affected.
+func (t *Trigger) Affected(files []string) bool {
+	if t.HasTag(TagAlways) {
+		return true
+	}
+	for _, pattern := range t.patterns() {
+		expanded, ok := t.Expand(pattern)
+		if !ok {
+			continue
+		}
+		for _, file := range files {
+			for dir := filepath.Clean(file); ; dir = filepath.Dir(dir) {
+				if match, _ := filepath.Match(expanded, dir); match {
+					return true
+				}
+				if dir == "/" || dir == "." {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
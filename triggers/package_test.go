@@ -0,0 +1,65 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	tr := Trigger{
+		Check: &Check{Paths: []string{"/usr/share/fonts"}, WatchPaths: []string{"/etc/fonts/conf.d"}},
+		Bins: []Bin{
+			{Bin: "/usr/bin/update", Args: []string{"-f", "/usr/share/icons", "relative"}},
+			{Bin: "/usr/bin/each", WorkingDir: "/usr/lib", Replace: &Replace{Paths: []string{"modules/*", "/opt/*"}}},
+		},
+	}
+	expected := []string{"/usr/share/fonts", "/etc/fonts/conf.d", "/usr/share/icons", "/usr/lib/modules/*", "/opt/*"}
+	if patterns := tr.patterns(); strings.Join(patterns, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected %v, got %v", expected, patterns)
+	}
+}
+
+func TestAffected(t *testing.T) {
+	fonts := Trigger{Name: "fonts", Check: &Check{Paths: []string{"/usr/share/fonts"}}}
+	icons := Trigger{Name: "icons", Bins: []Bin{{
+		Bin:     "/usr/bin/gtk-update-icon-cache",
+		Args:    []string{"***"},
+		Replace: &Replace{Paths: []string{"/usr/share/icons/*"}},
+	}}}
+	named := Trigger{Name: "named", Check: &Check{Paths: []string{"/usr/lib/%name%"}}}
+	always := Trigger{Name: "always", Tags: []string{TagAlways}}
+	tests := []struct {
+		name     string
+		trigger  Trigger
+		files    []string
+		expected bool
+	}{
+		{"exact path", fonts, []string{"/usr/share/fonts"}, true},
+		{"within a directory", fonts, []string{"/usr/share/fonts/TTF/a.ttf"}, true},
+		{"unrelated", fonts, []string{"/usr/share/fontsmore/a.ttf", "/usr/bin/fc-cache"}, false},
+		{"glob within", icons, []string{"/usr/share/icons/hicolor/16x16/a.png"}, true},
+		{"glob parent", icons, []string{"/usr/share/icons"}, false},
+		{"placeholder", named, []string{"/usr/lib/named/lib.so"}, true},
+		{"tagged always", always, nil, true},
+		{"no files", fonts, nil, false},
+	}
+	for _, test := range tests {
+		if actual := test.trigger.Affected(test.files); actual != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// searchPath joins the BinPath of a trigger into a PATH value
+func (t *Trigger) searchPath() string {
+	return strings.Join(t.BinPath, string(os.PathListSeparator))
+}
+
+// lookPath finds an executable, searching the BinPath of the trigger instead of the inherited
+// PATH when it is set
+func (t *Trigger) lookPath(bin string) (string, error) {
+	if len(t.BinPath) == 0 || strings.Contains(bin, "/") {
+		return exec.LookPath(bin)
+	}
+	for _, dir := range t.BinPath {
+		path := filepath.Join(dir, bin)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("executable file not found in %s", t.searchPath())
+}
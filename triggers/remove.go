@@ -18,25 +18,274 @@ import (
 	"fmt"
 	log "github.com/DataDrake/waterlog"
 	"github.com/getsolus/usysconf/state"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// unsafePaths may never be removed, no matter what a trigger asks for
+var unsafePaths = []string{
+	"/", "/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib64", "/proc",
+	"/root", "/run", "/sbin", "/sys", "/tmp", "/usr", "/var",
+}
+
 // Remove contains paths to be removed from the system.  Tis supports globbing.
+//
+// Paths may contain placeholders and variables (see Trigger.Expand), which are expanded before
+// the paths are globbed and checked for safety. A path with a variable that cannot be expanded is
+// skipped rather than being removed literally. Removal may be limited to a scope with Only.
+//
+// Paths must be absolute, unless they start with "~/", a placeholder or a variable, in which case
+// they must be absolute once expanded. Exclude is used as written, so it must always be absolute.
+//
+// When OlderThan is set (i.e. "12h" or "30d"), matching directories are walked and only the files
+// last modified before then are removed. RemoveEmpty also removes directories left empty.
+//
+// Symlinks are never followed by default: a matching symlink is removed itself, leaving its
+// target alone, and symlinks found while walking a directory are treated like files. With
+// FollowSymlinks, a matching symlink is resolved and its target is removed (or walked, for
+// OlderThan) as well as the link, as long as the target is also safe to remove. The target must also
+// lie within the tree the link was matched in, beneath the part of its pattern before any wildcard
+// (or the directory of the link, for a pattern without any).
 type Remove struct {
-	Paths   []string `toml:"paths"`
-	Exclude []string `toml:"exclude"`
+	Paths          []string `toml:"paths"`
+	Exclude        []string `toml:"exclude"`
+	Only           *Only    `toml:"only,omitempty"`
+	OlderThan      string   `toml:"older_than"`
+	RemoveEmpty    bool     `toml:"remove_empty"`
+	FollowSymlinks bool     `toml:"follow_symlinks"`
+
+	olderThan time.Duration
+}
+
+// Validate checks for errors in a Remove configuration
+func (r *Remove) Validate() (err error) {
+	if len(r.OlderThan) == 0 {
+		return nil
+	}
+	if days := strings.TrimSuffix(r.OlderThan, "d"); days != r.OlderThan {
+		var n int
+		if n, err = strconv.Atoi(days); err == nil {
+			r.olderThan = time.Duration(n) * 24 * time.Hour
+		}
+	} else {
+		r.olderThan, err = time.ParseDuration(r.OlderThan)
+	}
+	if err != nil || r.olderThan <= 0 {
+		return fmt.Errorf("invalid remove older_than '%s'", r.OlderThan)
+	}
+	return nil
+}
+
+// removal records the paths which would be removed during a dry-run, along with the sizes of files
+// (directories have a size of -1)
+type removal struct {
+	paths   []string
+	sizes   map[string]int64
+	planned map[string]bool
+	// roots are the trees the paths are matched in, see treeRoots
+	roots []string
+}
+
+// delete removes a single path, or only records it during a dry-run. Like the removal itself, a
+// dry-run fails for a directory which would not be empty by then.
+func (rm *removal) delete(path string, s Scope) error {
+	if !s.DryRun {
+		return os.Remove(path)
+	}
+	if rm.planned[path] {
+		return nil
+	}
+	var size int64
+	if info, err := os.Lstat(path); err == nil {
+		size = info.Size()
+		if info.IsDir() {
+			size = -1
+			empty, err := rm.empty(path)
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return &os.PathError{Op: "remove", Path: path, Err: syscall.ENOTEMPTY}
+			}
+		}
+	}
+	rm.paths = append(rm.paths, path)
+	rm.sizes[path] = size
+	rm.planned[path] = true
+	return nil
+}
+
+// contains checks if the target of a symlink lies within a tree which the link was matched in
+func (rm *removal) contains(link, target string) bool {
+	parent := filepath.Dir(link)
+	if resolved, err := filepath.EvalSymlinks(parent); err == nil {
+		parent = resolved
+	}
+	for _, root := range rm.roots {
+		if (parent == root || within(root, parent)) && within(root, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// empty checks if a directory is empty, or would be once the recorded paths are removed
+func (rm *removal) empty(dir string) (bool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if !rm.planned[filepath.Join(dir, entry.Name())] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// outputs lists the recorded paths along with their sizes, followed by the totals
+func (rm *removal) outputs() (outs []Output) {
+	var total int64
+	for _, path := range rm.paths {
+		subTask := fmt.Sprintf("would remove directory '%s'", path)
+		if size := rm.sizes[path]; size >= 0 {
+			subTask = fmt.Sprintf("would remove '%s' (%d bytes)", path, size)
+			total += size
+		}
+		outs = append(outs, Output{
+			Name:    "Removing paths",
+			Status:  Success,
+			SubTask: subTask,
+		})
+	}
+	return append(outs, Output{
+		Name:    "Removing paths",
+		Status:  Success,
+		SubTask: fmt.Sprintf("would remove %d path(s), %d bytes in total", len(rm.paths), total),
+	})
+}
+
+// reap removes the files under a path which are older than OlderThan
+func (r *Remove) reap(path string, s Scope, rm *removal) error {
+	cutoff := time.Now().Add(-r.olderThan)
+	var dirs []string
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		if !isSafe(p) {
+			return fmt.Errorf("refusing to remove unsafe path '%s'", p)
+		}
+		log.Debugf("    Removing path '%s'\n", p)
+		return rm.delete(p, s)
+	})
+	if err != nil || !r.RemoveEmpty {
+		return err
+	}
+	// Remove the deepest directories first, which may empty their parents
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		empty, err := rm.empty(dir)
+		if err != nil || !empty || !isSafe(dir) {
+			continue
+		}
+		log.Debugf("    Removing empty directory '%s'\n", dir)
+		if err := rm.delete(dir, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSafe checks that a path is absolute and is not a critical system directory
+func isSafe(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, unsafe := range unsafePaths {
+		if path == unsafe {
+			return false
+		}
+	}
+	return true
+}
+
+// treeRoots gets the directories which the patterns match paths within, the part of each pattern
+// before its first wildcard, with any symlinks resolved
+func treeRoots(patterns []string) (roots []string) {
+	for _, pattern := range patterns {
+		root := filepath.Dir(filepath.Clean(pattern))
+		parts := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+		for i, part := range parts {
+			if strings.ContainsAny(part, `*?[\`) {
+				root = strings.Join(parts[:i], string(filepath.Separator))
+				break
+			}
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		roots = append(roots, root)
+	}
+	return
+}
+
+// within checks if a path lies beneath a directory
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// removePaths expands the paths to be removed, skipping any which could not be fully expanded
+func (t *Trigger) removePaths() (paths []string) {
+	for _, path := range t.RemoveDirs.Paths {
+		expanded, ok := t.Expand(path)
+		if !ok {
+			log.Debugf("    Skipping path '%s', unable to expand it\n", path)
+			continue
+		}
+		paths = append(paths, expanded)
+	}
+	return
 }
 
 // Remove glob the paths and if it exists it will remove it from the system
 func (t *Trigger) Remove(s Scope) bool {
 	if s.DryRun {
-		log.Debugln("   No Paths will be removed during a dry-run\n")
+		log.Debugln("   Paths will only be listed during a dry-run\n")
 	}
 	if t.RemoveDirs == nil {
 		log.Debugln("   No Paths to remove\n")
 		return true
 	}
-	m, err := state.Scan(t.RemoveDirs.Paths)
+	if s.NoRemove {
+		log.Infof("    Removing paths is disabled, skipped for '%s'\n", t.Name)
+		t.Output = append(t.Output, Output{
+			Status:  Skipped,
+			Message: "removes being disabled",
+		})
+		return true
+	}
+	if !t.RemoveDirs.Only.Matches(s) {
+		log.Debugln("   No Paths to remove in this scope\n")
+		return true
+	}
+	patterns := t.removePaths()
+	m, err := state.Scan(patterns)
 	if err != nil {
 		out := Output{
 			Status:  Failure,
@@ -46,19 +295,71 @@ func (t *Trigger) Remove(s Scope) bool {
 		return false
 	}
 	m = m.Exclude(t.RemoveDirs.Exclude)
-	for k := range m {
-		log.Debugf("    Removing path '%s'\n", k)
-		if s.DryRun {
-			continue
-		}
-		if err := os.Remove(k); err != nil {
-			out := Output{
-				Status:  Failure,
-				Message: fmt.Sprintf("Failed to remove paths '%s', reason: %s\n", k, err),
+	paths := m.Strings()
+	sort.Strings(paths)
+	// Attempt every path, unless Strict, and report all of the failures together
+	var failures []string
+	rm := &removal{sizes: make(map[string]int64), planned: make(map[string]bool), roots: treeRoots(patterns)}
+	for _, k := range paths {
+		if err := t.RemoveDirs.remove(k, s, rm); err != nil {
+			failures = append(failures, fmt.Sprintf("'%s': %s", k, err))
+			if s.Strict {
+				break
 			}
-			t.Output = append(t.Output, out)
-			return false
 		}
 	}
+	if len(failures) > 0 {
+		out := Output{
+			Status:  Failure,
+			Message: fmt.Sprintf("Failed to remove paths:\n    %s\n", strings.Join(failures, "\n    ")),
+		}
+		t.Output = append(t.Output, out)
+		return false
+	}
+	// List everything which would have been removed
+	if s.DryRun {
+		t.Output = append(t.Output, rm.outputs()...)
+	}
 	return true
 }
+
+// remove deletes a single matched path, or the old files beneath it
+func (r *Remove) remove(path string, s Scope, rm *removal) error {
+	if !isSafe(path) {
+		return fmt.Errorf("refusing to remove unsafe path")
+	}
+	if r.FollowSymlinks {
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return r.removeLink(path, s, rm)
+		}
+	}
+	if r.olderThan > 0 {
+		return r.reap(path, s, rm)
+	}
+	log.Debugf("    Removing path '%s'\n", path)
+	return rm.delete(path, s)
+}
+
+// removeLink deletes the target of a symlink, followed by the link itself
+func (r *Remove) removeLink(path string, s Scope, rm *removal) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("unable to resolve symlink, reason: %s", err)
+	}
+	if !isSafe(target) {
+		return fmt.Errorf("refusing to remove unsafe symlink target '%s'", target)
+	}
+	if !rm.contains(path, target) {
+		return fmt.Errorf("refusing to remove symlink target '%s', outside of the matched paths", target)
+	}
+	if r.olderThan > 0 {
+		// Only the old files are removed, so the link may still be needed
+		return r.reap(target, s, rm)
+	}
+	log.Debugf("    Removing symlink target '%s'\n", target)
+	if err := rm.delete(target, s); err != nil {
+		return err
+	}
+	log.Debugf("    Removing path '%s'\n", path)
+	return rm.delete(path, s)
+}
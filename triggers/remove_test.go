@@ -0,0 +1,159 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOld is a modification time older than any OlderThan of the tests
+var testOld = time.Now().Add(-48 * time.Hour)
+
+// testTree creates a temporary directory with the files and directories (ending in "/") given,
+// returning its path and a function to clean up
+func testTree(t *testing.T, paths ...string) (string, func()) {
+	dir, err := ioutil.TempDir("", "usysconf-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		full := filepath.Join(dir, path)
+		if strings.HasSuffix(path, "/") {
+			err = os.MkdirAll(full, 0755)
+		} else {
+			err = ioutil.WriteFile(full, nil, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestRemoveDryRunNotEmpty(t *testing.T) {
+	dir, cleanup := testTree(t, "full/", "full/file", "empty/")
+	defer cleanup()
+	for _, dryRun := range []bool{true, false} {
+		tr := Trigger{Name: "remove", RemoveDirs: &Remove{Paths: []string{filepath.Join(dir, "*")}}}
+		if tr.Remove(Scope{DryRun: dryRun}) {
+			t.Fatalf("dry-run %t: expected removing a directory which is not empty to fail", dryRun)
+		}
+		out := tr.Output[len(tr.Output)-1]
+		if out.Status != Failure || !strings.Contains(out.Message, "directory not empty") {
+			t.Errorf("dry-run %t: expected the directory not being empty, got %s: %s", dryRun, out.Status, out.Message)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "full", "file")); err != nil {
+		t.Errorf("expected the file within the directory to be kept, reason: %s", err)
+	}
+}
+
+func TestRemoveDryRunEmptied(t *testing.T) {
+	dir, cleanup := testTree(t, "cache/", "cache/old/", "cache/old/file")
+	defer cleanup()
+	tr := Trigger{Name: "remove", RemoveDirs: &Remove{
+		Paths:       []string{filepath.Join(dir, "cache")},
+		OlderThan:   "1s",
+		RemoveEmpty: true,
+	}}
+	if err := tr.RemoveDirs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(dir, "cache", "old", "file")
+	if err := os.Chtimes(old, testOld, testOld); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Remove(Scope{DryRun: true}) {
+		t.Fatalf("expected the dry-run to succeed, got %v", tr.Output)
+	}
+	var listed []string
+	for _, out := range tr.Output {
+		listed = append(listed, out.SubTask)
+	}
+	expected := []string{
+		"would remove '" + old + "' (0 bytes)",
+		"would remove directory '" + filepath.Join(dir, "cache", "old") + "'",
+		"would remove directory '" + filepath.Join(dir, "cache") + "'",
+		"would remove 3 path(s), 0 bytes in total",
+	}
+	if strings.Join(listed, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(listed, "\n"))
+	}
+}
+
+func TestRemoveFollowSymlinkDir(t *testing.T) {
+	dir, cleanup := testTree(t, "cache/", "cache/real/", "cache/real/old", "cache/real/new")
+	defer cleanup()
+	link := filepath.Join(dir, "cache", "link")
+	if err := os.Symlink(filepath.Join(dir, "cache", "real"), link); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(dir, "cache", "real", "old")
+	if err := os.Chtimes(old, testOld, testOld); err != nil {
+		t.Fatal(err)
+	}
+	tr := Trigger{Name: "remove", RemoveDirs: &Remove{
+		Paths:          []string{filepath.Join(dir, "cache", "li*")},
+		OlderThan:      "1h",
+		FollowSymlinks: true,
+	}}
+	if err := tr.RemoveDirs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Remove(Scope{}) {
+		t.Fatalf("expected following the symlink to succeed, got %v", tr.Output)
+	}
+	if _, err := os.Lstat(old); !os.IsNotExist(err) {
+		t.Error("expected the old file within the target to be removed")
+	}
+	for _, kept := range []string{link, filepath.Join(dir, "cache", "real", "new")} {
+		if _, err := os.Lstat(kept); err != nil {
+			t.Errorf("expected '%s' to be kept, reason: %s", kept, err)
+		}
+	}
+}
+
+func TestRemoveFollowSymlinkOutside(t *testing.T) {
+	dir, cleanup := testTree(t, "cache/", "outside/", "outside/file")
+	defer cleanup()
+	outside := filepath.Join(dir, "outside", "file")
+	for _, name := range []string{"escape", "parent"} {
+		target := outside
+		if name == "parent" {
+			target = filepath.Join(dir, "cache")
+		}
+		if err := os.Symlink(target, filepath.Join(dir, "cache", name)); err != nil {
+			t.Fatal(err)
+		}
+		for _, pattern := range []string{filepath.Join(dir, "cache", "*"), filepath.Join(dir, "cache", name)} {
+			tr := Trigger{Name: "remove", RemoveDirs: &Remove{Paths: []string{pattern}, FollowSymlinks: true}}
+			if tr.Remove(Scope{}) {
+				t.Errorf("'%s': expected the target of '%s' to be refused", pattern, name)
+			}
+		}
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected the target outside of the matched paths to be kept, reason: %s", err)
+	}
+	inside := Trigger{Name: "remove", RemoveDirs: &Remove{Paths: []string{filepath.Join(dir, "*", "escape")}, FollowSymlinks: true}}
+	if !inside.Remove(Scope{DryRun: true}) {
+		t.Errorf("expected a target within the matched tree to be removed, got %v", inside.Output)
+	}
+}
@@ -14,9 +14,28 @@
 
 package triggers
 
+// DefaultFanOutEnv is the variable which receives the current path of a fan-out (or the paths of a
+// batch, one per line), unless Replace.Env is set
+const DefaultFanOutEnv = "USYSCONF_FANOUT"
+
 // Replace contains details to replace a single argument with a path in the
 // executed binary.  This supports globbing.
+//
+// Each invocation also receives its path in the Env variable (default: USYSCONF_FANOUT). Setting
+// Env explicitly fans out the bin even when none of its arguments are "***".
 type Replace struct {
 	Paths   []string `toml:"paths"`
 	Exclude []string `toml:"exclude"`
+	Env     string   `toml:"env"`
+	// Dedupe runs the bin once for each distinct path, when the Paths overlap. Since the current
+	// path is always passed to the bin, this drops exactly the invocations which would repeat one.
+	Dedupe bool `toml:"dedupe"`
+}
+
+// EnvName gets the variable which receives the current path of a fan-out
+func (r *Replace) EnvName() string {
+	if len(r.Env) > 0 {
+		return r.Env
+	}
+	return DefaultFanOutEnv
 }
@@ -0,0 +1,107 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	log "github.com/DataDrake/waterlog"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Retry contains details for re-running a binary which failed, or which claimed success but
+// printed output indicating otherwise.
+type Retry struct {
+	Attempts int    `toml:"attempts"`
+	Delay    string `toml:"delay"`
+	// OnOutput forces a retry when the output matches, even on a zero exit
+	OnOutput string `toml:"on_output"`
+	// Benign suppresses a retry when the output of a failed run matches
+	Benign string `toml:"benign"`
+
+	delay    time.Duration
+	onOutput *regexp.Regexp
+	benign   *regexp.Regexp
+}
+
+// Validate checks for errors in a Retry configuration and compiles its matchers
+func (r *Retry) Validate() (err error) {
+	if r.Attempts < 0 {
+		return fmt.Errorf("retry attempts must not be negative")
+	}
+	if len(r.Delay) > 0 {
+		if r.delay, err = time.ParseDuration(r.Delay); err != nil {
+			return fmt.Errorf("invalid retry delay '%s', reason: %s", r.Delay, err)
+		}
+	}
+	if len(r.OnOutput) > 0 {
+		if r.onOutput, err = regexp.Compile(r.OnOutput); err != nil {
+			return fmt.Errorf("invalid retry on_output '%s', reason: %s", r.OnOutput, err)
+		}
+	}
+	if len(r.Benign) > 0 {
+		if r.benign, err = regexp.Compile(r.Benign); err != nil {
+			return fmt.Errorf("invalid retry benign '%s', reason: %s", r.Benign, err)
+		}
+	}
+	return nil
+}
+
+// Check turns a successful run into a failure if its output matched OnOutput
+func (r *Retry) Check(err error, output []byte) error {
+	if err == nil && r.onOutput != nil && r.onOutput.Match(output) {
+		return fmt.Errorf("output matched '%s'", r.OnOutput)
+	}
+	return err
+}
+
+// ShouldRetry decides if a failed run should be attempted again
+func (r *Retry) ShouldRetry(err error, output []byte) bool {
+	if err == nil {
+		return false
+	}
+	return r.benign == nil || !r.benign.Match(output)
+}
+
+// RetryBudget limits the number of retries across a whole run, whether of a single Bin or of a
+// whole trigger, so that a flaky system cannot multiply the time taken without bound
+type RetryBudget struct {
+	lock sync.Mutex
+	left int
+}
+
+// NewRetryBudget creates a RetryBudget allowing n retries in total
+func NewRetryBudget(n int) *RetryBudget {
+	return &RetryBudget{left: n}
+}
+
+// take uses up a single retry, if any are left. A nil RetryBudget is unlimited.
+func (rb *RetryBudget) take() bool {
+	if rb == nil {
+		return true
+	}
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+	switch {
+	case rb.left > 0:
+		rb.left--
+		return true
+	case rb.left == 0:
+		log.Warnln("The retry budget for this run is used up, nothing more will be retried")
+		rb.left--
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	log "github.com/DataDrake/waterlog"
+)
+
+// Filter selects the triggers of a Map to run
+type Filter struct {
+	// Names of the triggers to run, every trigger is run when empty
+	Names []string
+	// Phase limits the triggers to those of a phase, see InPhase (default: all)
+	Phase string
+	// Files limits the triggers to those Affected by any of these files, when not nil
+	Files []string
+	// Shuffle runs the triggers in a random order, which is the same for the same Seed
+	Shuffle bool
+	Seed    int64
+}
+
+// Select finds the names of the triggers matching the filter, shuffled if requested. Names which
+// are not in the Map are kept, so that Run can warn about them.
+func (f Filter) Select(tm Map) (names []string, err error) {
+	if names, err = f.match(tm); err == nil && f.Shuffle {
+		Shuffle(names, f.Seed)
+	}
+	return
+}
+
+// match finds the names of the triggers matching the filter
+func (f Filter) match(tm Map) (names []string, err error) {
+	switch f.Phase {
+	case "", PhaseBoot, PhaseDeferred:
+	default:
+		return nil, fmt.Errorf("unsupported phase '%s'", f.Phase)
+	}
+	names = f.Names
+	if len(names) == 0 {
+		for k := range tm {
+			names = append(names, k)
+		}
+	}
+	if len(f.Phase) == 0 && f.Files == nil {
+		return
+	}
+	var filtered []string
+	for _, name := range names {
+		t, ok := tm[name]
+		if ok && len(f.Phase) > 0 && !t.InPhase(f.Phase) {
+			log.Debugf("Skipping trigger '%s', not in the '%s' phase\n", name, f.Phase)
+			continue
+		}
+		if ok && f.Files != nil && !t.Affected(f.Files) {
+			log.Debugf("Skipping trigger '%s', not affected by the files\n", name)
+			continue
+		}
+		filtered = append(filtered, name)
+	}
+	return filtered, nil
+}
+
+// TriggerResult contains the outcome of running a single trigger
+type TriggerResult struct {
+	Name   string
+	Status Status
+	Output []Output
+	// Trigger is the trigger as it was run, for reporting on more than its outputs
+	Trigger Trigger
+}
+
+// Results gets the outcome of each of the triggers returned by Run
+func Results(ts []Trigger) []TriggerResult {
+	results := make([]TriggerResult, 0, len(ts))
+	for _, t := range ts {
+		results = append(results, TriggerResult{
+			Name:    t.Name,
+			Status:  t.Status(),
+			Output:  t.Output,
+			Trigger: t,
+		})
+	}
+	return results
+}
+
+// Triggers gets the triggers which were run for each of the results, see Results
+func Triggers(results []TriggerResult) []Trigger {
+	ts := make([]Trigger, 0, len(results))
+	for _, r := range results {
+		ts = append(ts, r.Trigger)
+	}
+	return ts
+}
+
+// RunAll executes every trigger of a Map matching the filter, in the same way as the "run"
+// subcommand, returning the outcome of each
+func RunAll(tm Map, s Scope, filter Filter) ([]TriggerResult, error) {
+	names, err := filter.Select(tm)
+	if err != nil {
+		return nil, err
+	}
+	return Results(Run(tm, s, names)), nil
+}
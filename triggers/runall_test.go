@@ -0,0 +1,190 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"context"
+	"errors"
+	"github.com/getsolus/usysconf/state"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeExecutor records every Command instead of running it, failing those for the bins in fail
+type fakeExecutor struct {
+	lock     sync.Mutex
+	commands []Command
+	fail     map[string]bool
+}
+
+// Run fulfills the Executor interface
+func (f *fakeExecutor) Run(ctx context.Context, c Command) (Result, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commands = append(f.commands, c)
+	if f.fail[c.Bin] {
+		return Result{Output: []byte("failed\n")}, errors.New("exit status 1")
+	}
+	return Result{}, nil
+}
+
+// ran gets the command line of every Command which was run, in order
+func (f *fakeExecutor) ran() (lines []string) {
+	for _, c := range f.commands {
+		lines = append(lines, strings.Join(append([]string{c.Bin}, c.Args...), " "))
+	}
+	return
+}
+
+// testState points the state at a temporary directory, containing a file for the check paths of
+// the test triggers, returning the path of the file and a function to clean up
+func testState(t *testing.T) (checked string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "usysconf-triggers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked = filepath.Join(dir, "checked")
+	if err := ioutil.WriteFile(checked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	prev := state.Path
+	state.Path = filepath.Join(dir, "state")
+	return checked, func() {
+		state.Path = prev
+		os.RemoveAll(dir)
+	}
+}
+
+// testTrigger creates a trigger running each of the bins, with a check path which exists
+func testTrigger(name, checked string, bins ...string) Trigger {
+	t := Trigger{
+		Name:  name,
+		Check: &Check{Paths: []string{checked}},
+	}
+	for _, bin := range bins {
+		t.Bins = append(t.Bins, Bin{Bin: bin})
+	}
+	return t
+}
+
+func TestRunAll(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	exec := &fakeExecutor{fail: map[string]bool{"/bin/fail": true}}
+	tm := Map{
+		"good":    testTrigger("good", checked, "/bin/one", "/bin/two"),
+		"bad":     testTrigger("bad", checked, "/bin/fail"),
+		"partial": testTrigger("partial", checked, "/bin/three", "/bin/fail"),
+		"ignored": testTrigger("ignored", checked, "/bin/ignored"),
+	}
+	results, err := RunAll(tm, Scope{Executor: exec}, Filter{Names: []string{"good", "bad", "partial"}})
+	if err != nil {
+		t.Fatalf("RunAll: %s", err)
+	}
+	expected := map[string]Status{"good": Success, "bad": Failure, "partial": Partial}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for _, r := range results {
+		if r.Status != expected[r.Name] {
+			t.Errorf("expected '%s' to be %s, got %s", r.Name, expected[r.Name], r.Status)
+		}
+		if r.Trigger.Name != r.Name {
+			t.Errorf("expected the trigger of '%s', got '%s'", r.Name, r.Trigger.Name)
+		}
+	}
+	for _, line := range exec.ran() {
+		if line == "/bin/ignored" {
+			t.Error("expected the trigger outside of the filter not to be run")
+		}
+	}
+	if len(exec.commands) != 5 {
+		t.Errorf("expected 5 commands to be run, got %v", exec.ran())
+	}
+}
+
+func TestRunAllMaxFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    Scope
+		expected []Status
+	}{
+		{"no limit", Scope{}, []Status{Failure, Failure, Success}},
+		{"one failure", Scope{MaxFailures: 1}, []Status{Failure, Skipped, Skipped}},
+		{"two failures", Scope{MaxFailures: 2}, []Status{Failure, Failure, Skipped}},
+		{"fail fast", Scope{FailFast: true}, []Status{Failure, Skipped, Skipped}},
+	}
+	for _, test := range tests {
+		checked, cleanup := testState(t)
+		tm := Map{
+			"a": testTrigger("a", checked, "/bin/fail"),
+			"b": testTrigger("b", checked, "/bin/fail"),
+			"c": testTrigger("c", checked, "/bin/good"),
+		}
+		test.scope.Executor = &fakeExecutor{fail: map[string]bool{"/bin/fail": true}}
+		results, err := RunAll(tm, test.scope, Filter{Names: []string{"a", "b", "c"}})
+		if err != nil {
+			t.Fatalf("%s: RunAll: %s", test.name, err)
+		}
+		for i, r := range results {
+			if r.Status != test.expected[i] {
+				t.Errorf("%s: expected '%s' to be %s, got %s", test.name, r.Name, test.expected[i], r.Status)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestRunAllInvalidPhase(t *testing.T) {
+	if _, err := RunAll(Map{}, Scope{}, Filter{Phase: "later"}); err == nil {
+		t.Fatal("expected an unsupported phase to be rejected")
+	}
+}
+
+func TestRunAllDryRun(t *testing.T) {
+	checked, cleanup := testState(t)
+	defer cleanup()
+	exec := &fakeExecutor{}
+	tm := Map{"good": testTrigger("good", checked, "/bin/one")}
+	results, err := RunAll(tm, Scope{Executor: exec, DryRun: true}, Filter{})
+	if err != nil {
+		t.Fatalf("RunAll: %s", err)
+	}
+	if len(results) != 1 || results[0].Status != Success {
+		t.Fatalf("expected a single success, got %v", results)
+	}
+	if len(exec.commands) != 0 {
+		t.Errorf("expected nothing to be run, got %v", exec.ran())
+	}
+}
+
+func TestSelectShuffle(t *testing.T) {
+	tm := Map{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}}
+	filter := Filter{Shuffle: true, Seed: 42}
+	first, err := filter.Select(tm)
+	if err != nil {
+		t.Fatalf("Select: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		again, _ := filter.Select(tm)
+		if strings.Join(again, ",") != strings.Join(first, ",") {
+			t.Fatalf("expected the same order for the same seed, got %v and %v", first, again)
+		}
+	}
+}
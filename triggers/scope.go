@@ -14,6 +14,26 @@
 
 package triggers
 
+import (
+	"context"
+	"errors"
+	"github.com/getsolus/usysconf/util"
+	"text/template"
+	"time"
+)
+
+// errInterrupted is the reason for stopping the bins once the Scope.Context is cancelled
+var errInterrupted = errors.New("run interrupted")
+
+const (
+	// VerbosityCommands prints the command line of each bin as it is run
+	VerbosityCommands = 1
+	// VerbosityOutput also prints the full output of each bin
+	VerbosityOutput = 2
+	// VerbosityDebug also enables the debug output
+	VerbosityDebug = 3
+)
+
 // Scope sets limits of execution for a trigger
 type Scope struct {
 	Chroot bool
@@ -21,4 +41,105 @@ type Scope struct {
 	DryRun bool
 	Forced bool
 	Live   bool
+
+	// Verbosity is the level of detail printed about each bin, see VerbosityCommands
+	Verbosity int
+
+	// MaxOutputLines limits the output of a failed bin in its Message to the last lines, zero for all
+	MaxOutputLines int
+
+	// CollapseFailures prints consecutive failures of a bin with identical messages only once, with
+	// their count, i.e. for a large fan-out
+	CollapseFailures bool
+
+	// FailOnCheckError gives triggers whose check paths cannot be resolved the CheckError status,
+	// rather than failing them, or skipping them when a directory of the paths cannot be read
+	FailOnCheckError bool
+
+	// Strict turns suspicious conditions into failures
+	Strict bool
+	// ResourceStats prints the resources used by each bin
+	ResourceStats bool
+	// DumpEnv prints the environment of each bin before it is run
+	DumpEnv bool
+	// ExplainEnv prints the environment of each bin before it is run, with the origin of each variable
+	ExplainEnv bool
+	// PerDevice runs the invocations of a fanned-out bin in parallel, with one at a time for each
+	// device holding their paths
+	PerDevice bool
+	// SkipMissingBins skips triggers with bins that cannot be found, instead of failing them
+	SkipMissingBins bool
+	// NoRemove disables every Remove, without affecting the bins
+	NoRemove bool
+	// Confirm requires the operator to approve destructive triggers
+	Confirm bool
+	// MaxFailures is the number of failed triggers after which the rest are skipped, zero for no limit
+	MaxFailures int
+	// FailFast skips the rest of the triggers once one has failed, regardless of MaxFailures
+	FailFast bool
+	// RetryBudget limits the retries across every trigger, when set, taking precedence over the
+	// attempts of each Bin or trigger
+	RetryBudget *RetryBudget
+	// SinceLastRun skips triggers whose Check.WatchPaths are unchanged since their last success
+	SinceLastRun bool
+	// DefaultTimeout limits how long a bin without its own timeout may run, zero for no limit
+	DefaultTimeout time.Duration
+	// Events receives the progress of each trigger, when set
+	Events *Events
+	// Stream receives the result of each trigger as it finishes, when set
+	Stream *Stream
+	// Template replaces the printing of each trigger, rendering every Output with it, see
+	// ParseTemplate
+	Template *template.Template
+	// Executor runs the bins, defaulting to os/exec when unset
+	Executor Executor
+	// Context stops the run when cancelled (i.e. on an interrupt), killing the current bin and
+	// skipping the rest, defaulting to never
+	Context context.Context
+}
+
+// runContext gets the Context for this Scope
+func (s Scope) runContext() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
+}
+
+// interrupted checks if the run has been stopped, see Scope.Context
+func (s Scope) interrupted() bool {
+	return s.runContext().Err() != nil
+}
+
+// tolerates checks if the rest of the triggers may still run after a number of them failed, see
+// Scope.MaxFailures and Scope.FailFast
+func (s Scope) tolerates(failures int) bool {
+	switch {
+	case s.FailFast:
+		return failures == 0
+	case s.MaxFailures > 0:
+		return failures < s.MaxFailures
+	}
+	return true
+}
+
+// executor gets the Executor for this Scope
+func (s Scope) executor() Executor {
+	if s.Executor == nil {
+		return ExecExecutor{}
+	}
+	return s.Executor
+}
+
+// DetectScope fills in the parts of a Scope which can be detected from the running system
+func DetectScope(s Scope) Scope {
+	// Set Chroot as needed
+	if s.DryRun && util.IsChroot() {
+		s.Chroot = true
+	}
+	// Set Live as needed
+	if util.IsLive() {
+		s.Live = true
+	}
+	return s
 }
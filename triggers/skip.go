@@ -17,6 +17,10 @@ package triggers
 import (
 	"fmt"
 	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/util"
+	"os"
+	"runtime"
+	"time"
 )
 
 // Skip contains details for when the configuration will not be executed, due
@@ -25,6 +29,123 @@ type Skip struct {
 	Chroot bool     `toml:"chroot,omitempty"`
 	Live   bool     `toml:"live,omitempty"`
 	Paths  []string `toml:"paths"`
+	// OnBattery defers the trigger while the system is running from a battery
+	OnBattery bool `toml:"on_battery,omitempty"`
+	// Once skips the trigger after its first success, which is recorded in the state
+	Once bool `toml:"once,omitempty"`
+	// MinCPUs skips the trigger on systems with fewer CPUs
+	MinCPUs int `toml:"min_cpus,omitempty"`
+}
+
+// onceKey is the key in the state which records the success of a Skip.Once trigger. It can never
+// collide with a scanned path, since those are absolute.
+func onceKey(name string) string {
+	return "once:" + name
+}
+
+// lastRunKey is the key in the state which records the last success of a trigger
+func lastRunKey(name string) string {
+	return "lastrun:" + name
+}
+
+// carryRecords keeps the records of a trigger for the next run, whether or not it runs
+func (t *Trigger) carryRecords(prev, next state.Map) {
+	for _, key := range []string{onceKey(t.Name), lastRunKey(t.Name)} {
+		if when, ok := prev[key]; ok {
+			next[key] = when
+		}
+	}
+}
+
+// validateConditions rejects a Skip which contradicts the Only of the trigger or of its Remove,
+// since the trigger (or removal) could then never run
+func (t *Trigger) validateConditions() error {
+	if err := t.Skip.contradicts("only", t.Only); err != nil {
+		return err
+	}
+	if t.RemoveDirs != nil {
+		return t.Skip.contradicts("remove.only", t.RemoveDirs.Only)
+	}
+	return nil
+}
+
+// contradicts checks if the Skip excludes every Scope allowed by an Only, from the named section
+func (skip *Skip) contradicts(section string, only *Only) error {
+	switch {
+	case skip == nil || only == nil:
+	case skip.Chroot && only.Chroot:
+		return fmt.Errorf("skip.chroot and %s.chroot are contradictory, it could never run", section)
+	case skip.Live && only.Live:
+		return fmt.Errorf("skip.live and %s.live are contradictory, it could never run", section)
+	}
+	return nil
+}
+
+// ranOnce checks if a Skip.Once trigger has already succeeded
+func (t *Trigger) ranOnce(s Scope, prev state.Map) bool {
+	if t.Skip == nil || !t.Skip.Once || s.Forced {
+		return false
+	}
+	when, ok := prev[onceKey(t.Name)]
+	if !ok {
+		return false
+	}
+	t.Output = append(t.Output, Output{
+		Status:  Skipped,
+		Message: fmt.Sprintf("having already succeeded once, at %s", when.Format(time.RFC3339)),
+	})
+	return true
+}
+
+// unwatched checks if none of the Check.WatchPaths changed since the last success of the trigger,
+// when running in the SinceLastRun mode
+func (t *Trigger) unwatched(s Scope, prev state.Map) bool {
+	if !s.SinceLastRun || s.Forced || t.Check == nil || len(t.Check.WatchPaths) == 0 {
+		return false
+	}
+	last, ok := prev[lastRunKey(t.Name)]
+	if !ok {
+		return false
+	}
+	watched, err := state.Scan(t.Check.WatchPaths)
+	if err != nil {
+		return false
+	}
+	for _, modified := range watched {
+		if modified.After(last) {
+			return false
+		}
+	}
+	t.Output = append(t.Output, Output{
+		Status:  Skipped,
+		Message: fmt.Sprintf("no watched paths changing since the last run, at %s", last.Format(time.RFC3339)),
+	})
+	return true
+}
+
+// record keeps the checked paths and the records of a trigger in the state for the next run. The
+// current paths are only recorded once the trigger has succeeded, otherwise their previous records
+// are kept, so that a trigger which failed is run again on the next run even if its paths do not
+// change. The same goes for a trigger which was running when the run was interrupted, since it may
+// not have finished. A trigger waiting for coalesced bins is only recorded once they have run.
+func (t *Trigger) record(s Scope, prev, next state.Map) {
+	if t.Status() != Success || s.interrupted() {
+		for path := range t.checked {
+			if _, ok := next[path]; ok {
+				continue
+			}
+			if when, ok := prev[path]; ok {
+				next[path] = when
+			}
+		}
+		return
+	}
+	next.Merge(t.checked)
+	now := time.Now()
+	next[lastRunKey(t.Name)] = now
+	if t.Skip != nil && t.Skip.Once {
+		next[onceKey(t.Name)] = now
+	}
 }
 
 // ShouldSkip will process the skip and check elements of the configuration and see if it should not be executed.
@@ -38,12 +159,46 @@ func (t *Trigger) ShouldSkip(s Scope, check, diff state.Map) bool {
 		return true
 	}
 
+	// Run the check command, if any, and skip if it did not succeed
+	if t.Check != nil && t.Check.Exec != nil {
+		if reason, ok := t.Check.Exec.Run(s); !ok {
+			out.Message = reason
+			t.Output = append(t.Output, out)
+			return true
+		}
+	}
+
+	// Check the version of a required tool, and skip if it is not satisfied
+	if t.Check != nil && t.Check.Version != nil {
+		if reason, ok := t.Check.Version.Run(s); !ok {
+			out.Message = reason
+			t.Output = append(t.Output, out)
+			return true
+		}
+	}
+
+	// Check for the kernel modules of the subsystem, and skip if any are missing
+	if t.Check != nil && len(t.Check.Modules) > 0 {
+		if missing, ok := util.MissingModules(t.Check.Modules); !ok {
+			out.Message = fmt.Sprintf("the kernel module '%s' not being loaded", missing)
+			t.Output = append(t.Output, out)
+			return true
+		}
+	}
+
 	// Even if the skip element exists, if the force flag is present,
 	// continue processing
 	if s.Forced {
 		return false
 	}
 
+	// If the only element exists and does not match the scope or desktop, skip
+	if !t.Only.Matches(s) {
+		out.Message = fmt.Sprintf("the scope or desktop '%s' not matching [only]", os.Getenv("XDG_CURRENT_DESKTOP"))
+		t.Output = append(t.Output, out)
+		return true
+	}
+
 	if t.Skip == nil {
 		return false
 	}
@@ -60,6 +215,20 @@ func (t *Trigger) ShouldSkip(s Scope, check, diff state.Map) bool {
 		return true
 	}
 
+	// If the skip element exists and the system is on battery, skip
+	if t.Skip.OnBattery && util.OnBattery() {
+		out.Message = "running on battery"
+		t.Output = append(t.Output, out)
+		return true
+	}
+
+	// If the skip element exists and there are too few CPUs, skip
+	if n := runtime.NumCPU(); n < t.Skip.MinCPUs {
+		out.Message = fmt.Sprintf("having %d CPU(s), fewer than %d", n, t.Skip.MinCPUs)
+		t.Output = append(t.Output, out)
+		return true
+	}
+
 	// Process through the skip paths, and if one is present within the
 	// system, skip
 	matches := check.Search(t.Skip.Paths)
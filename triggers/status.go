@@ -14,6 +14,10 @@
 
 package triggers
 
+import (
+	"encoding/json"
+)
+
 // Status indicates the state of the configuration.
 type Status int
 
@@ -24,4 +28,31 @@ const (
 	Success
 	// Failure - The configuration was not be executed, due to error.
 	Failure
+	// Partial - Some of the bins of the configuration failed, while others succeeded.
+	Partial
+	// CheckError - The check paths could not be resolved, i.e. due to a bad pattern or a lack of
+	// permissions, see Scope.FailOnCheckError.
+	CheckError
 )
+
+// String gets the name of a Status
+func (s Status) String() string {
+	switch s {
+	case Skipped:
+		return "skipped"
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	case Partial:
+		return "partial"
+	case CheckError:
+		return "check-error"
+	}
+	return "unknown"
+}
+
+// MarshalJSON renders a Status by name
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
@@ -0,0 +1,53 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"encoding/json"
+	log "github.com/DataDrake/waterlog"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stream writes a "finish" Event for every trigger as soon as it completes, as one line of JSON
+// each (NDJSON), unlike the Summary which is only written at the end of a run
+type Stream struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// NewStream creates a Stream writing to w, which should not buffer the lines
+func NewStream(w io.Writer) *Stream {
+	return &Stream{w: w}
+}
+
+// Finish writes the final status of a trigger
+func (st *Stream) Finish(t *Trigger) {
+	if st == nil {
+		return
+	}
+	status := t.Status()
+	raw, err := json.Marshal(Event{Event: "finish", Time: time.Now().UTC(), Name: t.Name, Status: &status})
+	if err != nil {
+		log.Warnf("Failed to encode result, reason: %s\n", err)
+		return
+	}
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if _, err = st.w.Write(append(raw, '\n')); err != nil {
+		log.Warnf("Failed to stream result, reason: %s\n", err)
+	}
+}
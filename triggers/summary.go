@@ -0,0 +1,72 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Summary contains the overall results of a run
+type Summary struct {
+	Time      time.Time `json:"time"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Partial   int       `json:"partial"`
+	Skipped   int       `json:"skipped"`
+	// CheckErrors counts the triggers whose check paths could not be resolved, see CheckError
+	CheckErrors int              `json:"check_errors"`
+	Triggers    []TriggerSummary `json:"triggers"`
+}
+
+// TriggerSummary contains the result of a single trigger
+type TriggerSummary struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+}
+
+// Summarize counts up the results of a run
+func Summarize(results []Trigger) (s Summary) {
+	s.Time = time.Now().UTC()
+	s.Triggers = make([]TriggerSummary, 0, len(results))
+	for _, t := range results {
+		status := t.Status()
+		switch status {
+		case Success:
+			s.Succeeded++
+		case Failure:
+			s.Failed++
+		case Partial:
+			s.Partial++
+		case Skipped:
+			s.Skipped++
+		case CheckError:
+			s.CheckErrors++
+		}
+		s.Triggers = append(s.Triggers, TriggerSummary{
+			Name:   t.Name,
+			Status: status,
+		})
+	}
+	return
+}
+
+// WriteJSON renders the Summary as an indented JSON document
+func (s Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(s)
+}
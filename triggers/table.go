@@ -0,0 +1,195 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxWidth is the widest a column of the table may be when PrintOptions.MaxWidth is not set
+const defaultMaxWidth = 60
+
+// truncate shortens a string to a width, ending it with an ellipsis when it is too long
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// pad truncates a string to a width, padding it with spaces if it is too short
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", width-len([]rune(s)))
+}
+
+// Fprint renders a Map in a human-readable format, as a table with columns as wide as their
+// contents, up to PrintOptions.MaxWidth
+func Fprint(w io.Writer, tm Map, opts PrintOptions) {
+	var keys []string
+	for k := range tm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(opts.Tree) > 0 {
+		fprintTree(w, tm, keys, opts)
+		return
+	}
+	if opts.Plain {
+		fprintPlain(w, tm, keys, opts)
+		return
+	}
+	max := opts.MaxWidth
+	if max <= 0 {
+		max = defaultMaxWidth
+	}
+	// Size the columns to fit the widest value, or the heading
+	header := []string{"NAME", "DESCRIPTION", "BINS"}
+	widths := make([]int, len(header))
+	for i, heading := range header {
+		widths[i] = len(heading)
+	}
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		t := tm[key]
+		row := []string{t.Name, t.Description, strconv.Itoa(len(t.Bins))}
+		for i, field := range row {
+			if n := len([]rune(field)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+		rows = append(rows, row)
+	}
+	for i := range widths {
+		if widths[i] > max {
+			widths[i] = max
+		}
+	}
+	fmt.Fprintf(w, "%s  %s  %s\n", pad(header[0], widths[0]), pad(header[1], widths[1]), header[2])
+	// Details are indented to the description column
+	indent := strings.Repeat(" ", widths[0])
+	for i, row := range rows {
+		fmt.Fprintf(w, "%s  %s  %s\n", pad(row[0], widths[0]), pad(row[1], widths[1]), row[2])
+		for _, line := range details(tm[keys[i]], opts) {
+			fmt.Fprintf(w, "%s  %s\n", indent, line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// fprintPlain renders each trigger as "name - description", with the names aligned to the right
+func fprintPlain(w io.Writer, tm Map, keys []string, opts PrintOptions) {
+	max := 0
+	for _, key := range keys {
+		if len(key) > max {
+			max = len(key)
+		}
+	}
+	max += 4
+	f := fmt.Sprintf("%%%ds - %%s\n", max)
+	for _, key := range keys {
+		t := tm[key]
+		fmt.Fprintf(w, f, t.Name, t.Description)
+		for _, line := range details(t, opts) {
+			fmt.Fprintf(w, "%s      %s\n", strings.Repeat(" ", max), line)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// untagged is the group of the triggers without any Tags, in a tree
+const untagged = "(untagged)"
+
+// groups sorts the triggers into the groups of a tree, each in the order of keys. Grouping by tag
+// falls back to the phases when no trigger has any Tags, rather than a single group.
+func groups(tm Map, keys []string, by string) (names []string, members map[string][]string) {
+	members = make(map[string][]string)
+	if by == TreeTag {
+		for _, key := range keys {
+			tags := tm[key].Tags
+			if len(tags) == 0 {
+				tags = []string{untagged}
+			}
+			for _, tag := range tags {
+				members[tag] = append(members[tag], key)
+			}
+		}
+		if _, ok := members[untagged]; ok && len(members) == 1 {
+			by = TreePhase
+			members = make(map[string][]string)
+		}
+	}
+	if by == TreePhase {
+		for _, key := range keys {
+			phase := tm[key].Phase
+			if len(phase) == 0 {
+				phase = PhaseBoot
+			}
+			members[phase] = append(members[phase], key)
+		}
+	}
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// fprintTree renders the triggers beneath the groups they belong to, as "name - description"
+func fprintTree(w io.Writer, tm Map, keys []string, opts PrintOptions) {
+	names, members := groups(tm, keys, opts.Tree)
+	for _, name := range names {
+		fmt.Fprintln(w, name)
+		for i, key := range members[name] {
+			branch, stem := "├── ", "│   "
+			if i == len(members[name])-1 {
+				branch, stem = "└── ", "    "
+			}
+			t := tm[key]
+			if len(t.Description) > 0 {
+				fmt.Fprintf(w, "%s%s - %s\n", branch, t.Name, t.Description)
+			} else {
+				fmt.Fprintf(w, "%s%s\n", branch, t.Name)
+			}
+			for _, line := range details(t, opts) {
+				fmt.Fprintf(w, "%s    %s\n", stem, line)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// details gets the lines printed beneath a trigger, for the declared conditions and bins
+func details(t Trigger, opts PrintOptions) (lines []string) {
+	if opts.Gating {
+		lines = append(lines, t.Gating()...)
+	}
+	if !opts.Verbose {
+		return
+	}
+	for _, b := range t.Bins {
+		if len(b.Description) > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", b.Task, b.Description))
+		} else {
+			lines = append(lines, b.Task)
+		}
+	}
+	return
+}
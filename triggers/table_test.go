@@ -0,0 +1,163 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFprintTreeTags(t *testing.T) {
+	tm := Map{
+		"fonts":    Trigger{Name: "fonts", Description: "Rebuild the font cache", Tags: []string{"desktop", "cache"}},
+		"icons":    Trigger{Name: "icons", Tags: []string{"desktop"}},
+		"ldconfig": Trigger{Name: "ldconfig", Description: "Update the linker cache"},
+		"mime":     Trigger{Name: "mime", Tags: []string{"desktop"}},
+	}
+	expected := `(untagged)
+└── ldconfig - Update the linker cache
+cache
+└── fonts - Rebuild the font cache
+desktop
+├── fonts - Rebuild the font cache
+├── icons
+└── mime
+
+`
+	var buff bytes.Buffer
+	Fprint(&buff, tm, PrintOptions{Tree: TreeTag})
+	if buff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buff.String())
+	}
+}
+
+func TestFprintTreePhaseFallback(t *testing.T) {
+	tm := Map{
+		"fonts":    Trigger{Name: "fonts", Phase: PhaseDeferred},
+		"icons":    Trigger{Name: "icons", Phase: PhaseDeferred},
+		"ldconfig": Trigger{Name: "ldconfig"},
+	}
+	// Nothing is tagged, so grouping by tag is no better than a single group
+	expected := `boot
+└── ldconfig
+deferred
+├── fonts
+└── icons
+
+`
+	for _, by := range []string{TreeTag, TreePhase} {
+		var buff bytes.Buffer
+		Fprint(&buff, tm, PrintOptions{Tree: by})
+		if buff.String() != expected {
+			t.Errorf("expected grouping by %s to give:\n%s\ngot:\n%s", by, expected, buff.String())
+		}
+	}
+}
+
+func TestFprintTreeDetails(t *testing.T) {
+	tm := Map{
+		"fonts": Trigger{Name: "fonts", Tags: []string{"desktop"}, Bins: []Bin{{Task: "Rebuilding the cache"}}},
+		"icons": Trigger{Name: "icons", Tags: []string{"desktop"}, Bins: []Bin{{Task: "Updating", Description: "each theme"}}},
+	}
+	// The stem continues beside the details of every trigger but the last
+	expected := `desktop
+├── fonts
+│       Rebuilding the cache
+└── icons
+        Updating: each theme
+
+`
+	var buff bytes.Buffer
+	Fprint(&buff, tm, PrintOptions{Tree: TreeTag, Verbose: true})
+	if buff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buff.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"fonts", 0, "fonts"},
+		{"fonts", -1, "fonts"},
+		{"fonts", 5, "fonts"},
+		{"fonts", 8, "fonts"},
+		{"fonts", 4, "fon…"},
+		{"fonts", 1, "…"},
+		// Widths are counted in runes, not bytes
+		{"naïve café", 6, "naïve…"},
+	} {
+		if actual := truncate(tc.s, tc.width); actual != tc.expected {
+			t.Errorf("expected '%s' truncated to %d to be '%s', got '%s'", tc.s, tc.width, tc.expected, actual)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		width    int
+		expected string
+	}{
+		{"fonts", 5, "fonts"},
+		{"fonts", 8, "fonts   "},
+		{"fonts", 4, "fon…"},
+		{"café", 6, "café  "},
+		{"", 3, "   "},
+	} {
+		if actual := pad(tc.s, tc.width); actual != tc.expected {
+			t.Errorf("expected '%s' padded to %d to be '%s', got '%s'", tc.s, tc.width, tc.expected, actual)
+		}
+	}
+}
+
+func TestFprintTable(t *testing.T) {
+	tm := Map{
+		"fonts": Trigger{Name: "fonts", Description: "Rebuild the font cache", Bins: []Bin{{}, {}}},
+		"ld":    Trigger{Name: "ld", Description: "Update the linker cache", Bins: []Bin{{}}},
+	}
+	// The columns fit the widest value or heading
+	expected := `NAME   DESCRIPTION              BINS
+fonts  Rebuild the font cache   2
+ld     Update the linker cache  1
+
+`
+	var buff bytes.Buffer
+	Fprint(&buff, tm, PrintOptions{})
+	if buff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buff.String())
+	}
+}
+
+func TestFprintTableMaxWidth(t *testing.T) {
+	tm := Map{
+		"fonts": Trigger{Name: "fonts", Description: "Rebuild the font cache", Bins: []Bin{{Task: "Rebuilding"}}},
+		"ld":    Trigger{Name: "ld", Description: "Update the linker cache"},
+	}
+	// Wider values are truncated, while the details beneath are indented to the description
+	expected := `NAME   DESCRIPTION  BINS
+fonts  Rebuild th…  1
+       Rebuilding
+ld     Update the…  0
+
+`
+	var buff bytes.Buffer
+	Fprint(&buff, tm, PrintOptions{MaxWidth: 11, Verbose: true})
+	if buff.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buff.String())
+	}
+}
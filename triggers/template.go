@@ -0,0 +1,76 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate renders each Output much like the default printer, as a starting point for a
+// custom Scope.Template
+const DefaultTemplate = `{{.TriggerName}}{{with .Name}} {{.}}{{end}}: {{.Status}}{{with .SubTask}} for {{.}}{{end}}{{with trim .Message}} due to {{.}}{{end}}`
+
+// templateFuncs are the functions available to a Scope.Template, in addition to the builtins
+var templateFuncs = template.FuncMap{
+	"trim": strings.TrimSpace,
+}
+
+// ParseTemplate compiles a text/template for printing a single Output, with "trim" available
+// to remove the trailing newlines of a Message. The template is also tried on an empty Output, so
+// that references to unknown fields are caught before running any triggers.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err = tmpl.Execute(ioutil.Discard, Output{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// printTemplate prints every Output of the trigger to stdout with the Scope.Template, see fprintTemplate
+func (t *Trigger) printTemplate(s Scope) {
+	t.fprintTemplate(os.Stdout, s)
+}
+
+// fprintTemplate writes every Output of the trigger with the Scope.Template, leaving out those which
+// render to nothing but whitespace. Like the default printer, Skipped outputs are only shown when
+// debugging.
+func (t *Trigger) fprintTemplate(w io.Writer, s Scope) {
+	var buff bytes.Buffer
+	for _, out := range t.Output {
+		if out.Status == Skipped && log.Level() < level.Debug {
+			continue
+		}
+		buff.Reset()
+		if err := s.Template.Execute(&buff, out); err != nil {
+			log.Warnf("Failed to print the output of %s, reason: %s\n", t.Name, err)
+			return
+		}
+		if len(bytes.TrimSpace(buff.Bytes())) == 0 {
+			continue
+		}
+		buff.WriteByte('\n')
+		w.Write(buff.Bytes())
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"bytes"
+	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	for _, text := range []string{DefaultTemplate, "{{.TriggerName}} {{.Status}}", "{{trim .Message}}"} {
+		if _, err := ParseTemplate(text); err != nil {
+			t.Errorf("expected '%s' to parse, got %s", text, err)
+		}
+	}
+	// Caught before running anything, by rendering an empty Output
+	for _, text := range []string{"{{.Missing}}", "{{.Status", "{{nope .Message}}"} {
+		if _, err := ParseTemplate(text); err == nil {
+			t.Errorf("expected '%s' to be rejected", text)
+		}
+	}
+}
+
+func TestPrintTemplate(t *testing.T) {
+	defer log.SetLevel(log.Level())
+	tmpl, err := ParseTemplate(DefaultTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %s", err)
+	}
+	tr := Trigger{
+		Name: "fonts",
+		Output: []Output{
+			{TriggerName: "fonts", Name: "cache", Status: Success},
+			{TriggerName: "fonts", Name: "scale", Status: Failure, SubTask: "/usr/share/fonts", Message: "  exit status 1\n\n"},
+			{TriggerName: "fonts", Status: Skipped, Message: "no changes"},
+		},
+	}
+	s := Scope{Template: tmpl}
+	for _, tc := range []struct {
+		level    uint8
+		expected string
+	}{
+		{level.Info, "fonts cache: success\nfonts scale: failure for /usr/share/fonts due to exit status 1\n"},
+		{level.Debug, "fonts cache: success\nfonts scale: failure for /usr/share/fonts due to exit status 1\nfonts: skipped due to no changes\n"},
+	} {
+		log.SetLevel(tc.level)
+		var buff bytes.Buffer
+		tr.fprintTemplate(&buff, s)
+		if buff.String() != tc.expected {
+			t.Errorf("expected at level %d:\n%q\ngot:\n%q", tc.level, tc.expected, buff.String())
+		}
+	}
+}
+
+func TestPrintTemplateBlank(t *testing.T) {
+	tmpl, err := ParseTemplate("{{with trim .Message}}{{.}}{{end}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate: %s", err)
+	}
+	tr := Trigger{Output: []Output{{Status: Success, Message: " \n"}, {Status: Success, Message: "done\n"}}}
+	var buff bytes.Buffer
+	tr.fprintTemplate(&buff, Scope{Template: tmpl})
+	if buff.String() != "done\n" {
+		t.Errorf("expected outputs rendering to whitespace to be left out, got %q", buff.String())
+	}
+}
@@ -15,89 +15,324 @@
 package triggers
 
 import (
+	"fmt"
 	log "github.com/DataDrake/waterlog"
+	"github.com/DataDrake/waterlog/level"
 	"github.com/getsolus/usysconf/state"
+	"github.com/getsolus/usysconf/util"
+	"strings"
+	"time"
 )
 
 // Trigger contains all the information for a configuration to be executed and
 // output to the user.
 type Trigger struct {
-	Name   string
-	Path   string
-	Output []Output
-
-	Description string            `toml:"description"`
-	Bins        []Bin             `toml:"bins"`
-	Skip        *Skip             `toml:"skip,omitempty"`
-	Check       *Check            `toml:"check,omitempty"`
-	Env         map[string]string `toml:"env"`
-	RemoveDirs  *Remove           `toml:"remove,omitempty"`
+	Name   string   `toml:"-"`
+	Path   string   `toml:"-"`
+	Output []Output `toml:"-"`
+	// Archive is the archive the trigger was read from, if any, in which case Path is "<archive>:<entry>"
+	Archive string `toml:"-"`
+
+	Description string `toml:"description"`
+	// Bins are always run in the order they are declared, see ExecuteBins
+	Bins  []Bin             `toml:"bins"`
+	Skip  *Skip             `toml:"skip,omitempty"`
+	Only  *Only             `toml:"only,omitempty"`
+	Check *Check            `toml:"check,omitempty"`
+	Env   map[string]string `toml:"env"`
+	// EnvFile is a systemd EnvironmentFile whose variables are passed to the bins beneath the Env,
+	// and which may be referenced by it. Unlike the Env alone, the inherited environment is kept
+	// beneath both. A leading '-' ignores the file when it is missing.
+	EnvFile    string  `toml:"env_file"`
+	RemoveDirs *Remove `toml:"remove,omitempty"`
+	CPU        *CPU    `toml:"cpu,omitempty"`
+	// LogFile receives the output of every bin, "%name%" is replaced by the trigger name
+	LogFile string `toml:"log_file"`
+	// RunAfterChanged limits the trigger to running after one of these triggers did work, in the same run
+	RunAfterChanged []string `toml:"run_after_changed"`
+	// Timeout limits the total time taken by all of the bins, i.e. "10m", the rest are skipped once it runs out
+	Timeout string `toml:"timeout"`
+	// Retries runs all of the bins again, up to this many times, when the trigger fails or is Partial.
+	// The paths are only removed once, before the first attempt, and each attempt has its own Timeout.
+	Retries int `toml:"retries"`
+	// RetryDelay is the time to wait between attempts, i.e. "5s"
+	RetryDelay string `toml:"retry_delay"`
+	// Phase is when the trigger must run during boot, either "boot" (the default) or "deferred"
+	Phase string `toml:"phase"`
+	// BinPath replaces the inherited PATH of the bins, which are only searched for in these directories
+	BinPath []string `toml:"path"`
+	// Tags are labels for selecting triggers, see TagAlways
+	Tags []string `toml:"tags"`
+	// Mask lists the variables (with globbing) whose values are hidden from diagnostics
+	Mask []string `toml:"mask"`
+	// LogLevel replaces the log level while this trigger runs, i.e. "debug" or "warn"
+	LogLevel string `toml:"log_level"`
+
+	deferred   []deferredBin
+	timeout    time.Duration
+	retryDelay time.Duration
+	attempts   int
+	native     Native
+	logLevel   uint8
+	checked    state.Map
+	lint       []string
+}
+
+const (
+	// PhaseBoot triggers must finish before the desktop starts
+	PhaseBoot = "boot"
+	// PhaseDeferred triggers may be run lazily once the system is up
+	PhaseDeferred = "deferred"
+)
+
+// InPhase checks if the trigger belongs to a phase, triggers without one belong to PhaseBoot
+func (t *Trigger) InPhase(phase string) bool {
+	if len(t.Phase) == 0 {
+		return phase == PhaseBoot
+	}
+	return t.Phase == phase
 }
 
 // Run will process a single configuration and scope.
 func (t *Trigger) Run(s Scope, prev, next state.Map) (ok bool) {
 	var check, diff state.Map
+	// Restored even when a bin panics
+	if t.logLevel != level.Disable {
+		defer log.SetLevel(log.Level())
+		log.SetLevel(t.logLevel)
+	}
+	s.Events.Start(t)
+	t.carryRecords(prev, next)
 	// Get the new check result
-	check, ok = t.CheckMatch()
+	check, ok = t.CheckMatch(s)
 	if !ok {
 		goto FINISH
 	}
 	// Calculate Diff
 	diff = state.Diff(prev, check)
-	// Merge it into the new State
-	next.Merge(diff)
 	// Check for Skip
-	if t.ShouldSkip(s, check, diff) {
+	if t.ShouldSkip(s, check, diff) || t.ranOnce(s, prev) || t.unwatched(s, prev) {
+		goto FINISH
+	}
+	// Ask before doing anything destructive
+	if s.Confirm && t.IsDestructive() && !t.confirm() {
+		goto FINISH
+	}
+	// Skip triggers which cannot run on this system
+	if s.SkipMissingBins && t.missingBins() {
 		goto FINISH
 	}
 	// Do the removals
 	if ok = t.Remove(s); !ok {
 		goto FINISH
 	}
+	// Point out arguments which will not be split like a shell would
+	if s.Strict {
+		t.warnArgs()
+	}
 	// Run the bins
-	t.ExecuteBins(s)
+	t.executeAttempts(s)
 FINISH:
+	t.checked = check
+	// Triggers waiting for coalesced bins are recorded once those have run, see RunWithState
+	if len(t.deferred) == 0 {
+		t.record(s, prev, next)
+	}
 	t.Finish(s)
 	return
 }
 
-// Finish is the last function to be executed by any trigger to output details to the user.
-func (t *Trigger) Finish(s Scope) {
-	// Check for the worst status
+// executeAttempts runs the bins, running all of them again while the trigger fails and Retries remain.
+// Only the outputs of the last attempt are kept.
+func (t *Trigger) executeAttempts(s Scope) {
+	outputs, deferred := len(t.Output), len(t.deferred)
+	for t.attempts = 1; ; t.attempts++ {
+		t.ExecuteBins(s)
+		if status := t.Status(); t.attempts > t.Retries || s.DryRun || s.interrupted() || (status != Failure && status != Partial) {
+			return
+		}
+		if !s.RetryBudget.take() {
+			return
+		}
+		log.Warnf("%s failed, retrying (attempt %d of %d)\n", t.Name, t.attempts+1, t.Retries+1)
+		t.Output, t.deferred = t.Output[:outputs], t.deferred[:deferred]
+		time.Sleep(t.retryDelay)
+	}
+}
+
+// missingBins checks if any of the bins cannot be found, adding a skip for the first one
+func (t *Trigger) missingBins() bool {
+	for _, b := range t.Bins {
+		if _, err := t.lookPath(b.Bin); err != nil {
+			t.Output = append(t.Output, Output{
+				Status:  Skipped,
+				Message: fmt.Sprintf("missing executable '%s'", b.Bin),
+			})
+			return true
+		}
+	}
+	return false
+}
+
+// IsDestructive checks if the trigger removes paths or runs any Dangerous bins
+func (t *Trigger) IsDestructive() bool {
+	if t.RemoveDirs != nil {
+		return true
+	}
+	for _, b := range t.Bins {
+		if b.Dangerous {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks the operator if a destructive trigger should be run
+func (t *Trigger) confirm() bool {
+	ok, err := util.Confirm(fmt.Sprintf("Trigger '%s' is destructive, run it anyway?", t.Name))
+	if err != nil {
+		t.Output = append(t.Output, Output{
+			Status:  Skipped,
+			Message: fmt.Sprintf("lack of confirmation, reason: %s", err),
+		})
+		return false
+	}
+	if !ok {
+		t.Output = append(t.Output, Output{
+			Status:  Skipped,
+			Message: "being declined by the operator",
+		})
+	}
+	return ok
+}
+
+// Status finds the worst status of all the outputs of this trigger, or Partial when some of the
+// outputs failed while others succeeded
+func (t *Trigger) Status() Status {
 	status := Skipped
 	for _, out := range t.Output {
 		if out.Status > status {
 			status = out.Status
 		}
 	}
+	if succeeded, _ := t.counts(); status == Failure && succeeded > 0 {
+		status = Partial
+	}
+	return status
+}
+
+// repeatedFailures counts the consecutive failures of the same bin from an Output with an identical
+// message, when they are collapsed for the Scope
+func (t *Trigger) repeatedFailures(s Scope, start int) (n int) {
+	first := t.Output[start]
+	if !s.CollapseFailures || first.Status != Failure || len(first.Message) == 0 {
+		return 1
+	}
+	for _, out := range t.Output[start:] {
+		if out.Status != Failure || out.Name != first.Name || out.Bin != first.Bin || out.Message != first.Message {
+			break
+		}
+		n++
+	}
+	return
+}
+
+// printRepeated prints a run of n identical failures as one, with the sub-tasks left for debugging
+func (t *Trigger) printRepeated(start, n int) {
+	out := t.Output[start]
+	prefix := "    "
+	if len(out.Name) > 0 {
+		prefix += out.Label() + " "
+	}
+	log.Errorf("%sFailure due to %s (×%d)\n", prefix, strings.TrimSpace(out.Message), n)
+	var subtasks []string
+	for _, repeat := range t.Output[start : start+n] {
+		if len(repeat.SubTask) > 0 {
+			subtasks = append(subtasks, repeat.SubTask)
+		}
+	}
+	if len(subtasks) > 0 {
+		log.Debugf("%sFailed for %s\n", prefix, strings.Join(subtasks, ", "))
+	}
+}
+
+// counts finds how many of the outputs of this trigger succeeded, out of those which were not skipped
+func (t *Trigger) counts() (succeeded, total int) {
+	for _, out := range t.Output {
+		switch out.Status {
+		case Success:
+			succeeded++
+			total++
+		case Failure, CheckError:
+			total++
+		}
+	}
+	return
+}
+
+// Finish is the last function to be executed by any trigger to output details to the user.
+func (t *Trigger) Finish(s Scope) {
+	for i := range t.Output {
+		if len(t.Output[i].TriggerName) == 0 {
+			t.Output[i].TriggerName = t.Name
+		}
+	}
+	s.Events.Finish(t)
+	// The final status of a trigger waiting for coalesced bins is streamed once those have run
+	if len(t.deferred) == 0 {
+		s.Stream.Finish(t)
+	}
+	if s.Template != nil {
+		t.printTemplate(s)
+		return
+	}
 	// Indicate the worst status for the whole group
-	switch status {
+	switch t.Status() {
 	case Skipped:
 		log.Debugln(t.Name)
-	case Failure:
+	case Failure, CheckError:
 		log.Errorln(t.Name)
+	case Partial:
+		succeeded, total := t.counts()
+		log.Errorf("%s (%d/%d succeeded)\n", t.Name, succeeded, total)
 	case Success:
 		log.Goodln(t.Name)
 	}
+	if t.attempts > 1 {
+		log.Infof("    Ran %d attempts of the whole trigger\n", t.attempts)
+	}
 	// Indicate status for sub-tasks
-	for _, out := range t.Output {
+	for i := 0; i < len(t.Output); i++ {
+		out := t.Output[i]
+		if n := t.repeatedFailures(s, i); n > 1 {
+			t.printRepeated(i, n)
+			i += n - 1
+			continue
+		}
+		prefix := "    "
+		if len(out.Name) > 0 {
+			prefix += out.Label() + " "
+		}
 		switch out.Status {
 		case Skipped:
 			if len(out.SubTask) > 0 {
-				log.Debugf("    Skipped for %s due to %s\n", out.SubTask, out.Message)
+				log.Debugf("%sSkipped for %s due to %s\n", prefix, out.SubTask, out.Message)
 			} else if len(out.Message) > 0 {
-				log.Debugf("    Skipped due to %s\n", out.Message)
+				log.Debugf("%sSkipped due to %s\n", prefix, out.Message)
 			}
-		case Failure:
+		case Failure, CheckError:
 			if len(out.SubTask) > 0 {
-				log.Errorf("    Failure for %s due to %s\n", out.SubTask, out.Message)
+				log.Errorf("%sFailure for %s due to %s\n", prefix, out.SubTask, out.Message)
 			} else if len(out.Message) > 0 {
-				log.Errorf("    Failure due to %s\n", out.Message)
+				log.Errorf("%sFailure due to %s\n", prefix, out.Message)
 			}
 		case Success:
 			if s.DryRun && len(out.SubTask) > 0 {
-				log.Infof("    %s\n", out.SubTask)
+				log.Infof("%s%s\n", prefix, out.SubTask)
 			}
 		}
+		if s.ResourceStats && out.Status != Skipped && !s.DryRun {
+			log.Infof("%s%s\n", prefix, out.Usage)
+		}
 	}
 }
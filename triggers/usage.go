@@ -0,0 +1,33 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"time"
+)
+
+// Usage contains the resources consumed by a bin, left zeroed where unavailable
+type Usage struct {
+	// MaxRSS is the peak resident memory, in KiB
+	MaxRSS int64
+	User   time.Duration
+	System time.Duration
+}
+
+// String renders a Usage in a human-readable format
+func (u Usage) String() string {
+	return fmt.Sprintf("maxrss: %d KiB, user: %s, sys: %s", u.MaxRSS, u.User, u.System)
+}
@@ -0,0 +1,36 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// usageOf gets the resource usage of a finished process
+func usageOf(state *os.ProcessState) (u Usage) {
+	if state == nil {
+		return
+	}
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return
+	}
+	u.MaxRSS = ru.Maxrss
+	u.User = time.Duration(ru.Utime.Nano())
+	u.System = time.Duration(ru.Stime.Nano())
+	return
+}
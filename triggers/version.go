@@ -0,0 +1,155 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultVersionRegex finds the first dotted number in the output of a version command
+const defaultVersionRegex = `(\d+(?:\.\d+)*)`
+
+// Version contains a command whose output must report a version satisfying a constraint for a
+// trigger to run, i.e. { bin = "gtk-update-icon-cache", args = ["--version"], constraint = ">= 3.24" }
+type Version struct {
+	Bin  string   `toml:"bin"`
+	Args []string `toml:"args"`
+	// Regex extracts the version from the output, using the first group if it has one
+	Regex string `toml:"regex"`
+	// Constraint is a comparison (<, <=, =, !=, >= or >) with a version, several may be separated by ','
+	Constraint string `toml:"constraint"`
+
+	regex       *regexp.Regexp
+	constraints []constraint
+}
+
+// constraint is a single comparison with a version
+type constraint struct {
+	op      string
+	version []int
+}
+
+// Validate checks for errors in a Version configuration
+func (v *Version) Validate() (err error) {
+	if len(v.Bin) == 0 {
+		return fmt.Errorf("check version must specify a bin")
+	}
+	regex := v.Regex
+	if len(regex) == 0 {
+		regex = defaultVersionRegex
+	}
+	if v.regex, err = regexp.Compile(regex); err != nil {
+		return fmt.Errorf("invalid check version regex '%s', reason: %s", v.Regex, err)
+	}
+	v.constraints = nil
+	for _, raw := range strings.Split(v.Constraint, ",") {
+		raw = strings.TrimSpace(raw)
+		c := constraint{op: "="}
+		for _, op := range []string{"<=", ">=", "!=", "==", "<", ">", "="} {
+			if strings.HasPrefix(raw, op) {
+				c.op = op
+				raw = strings.TrimSpace(strings.TrimPrefix(raw, op))
+				break
+			}
+		}
+		if c.version, err = parseVersion(raw); err != nil {
+			return fmt.Errorf("invalid check version constraint '%s'", v.Constraint)
+		}
+		v.constraints = append(v.constraints, c)
+	}
+	return nil
+}
+
+// parseVersion splits a dotted version into its numbers
+func parseVersion(raw string) (version []int, err error) {
+	for _, piece := range strings.Split(raw, ".") {
+		n, err := strconv.Atoi(piece)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version '%s'", raw)
+		}
+		version = append(version, n)
+	}
+	return
+}
+
+// compareVersions orders two versions, treating missing numbers as zero
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// satisfies checks a version against a single constraint
+func (c constraint) satisfies(version []int) bool {
+	cmp := compareVersions(version, c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// Run executes the command, returning a reason if the version did not satisfy the constraint
+func (v *Version) Run(s Scope) (reason string, ok bool) {
+	ctx, cancel := context.WithTimeout(s.runContext(), defaultExecTimeout)
+	defer cancel()
+	res, err := s.executor().Run(ctx, Command{Bin: v.Bin, Args: v.Args})
+	if err != nil {
+		return fmt.Sprintf("version check '%s' failing to run, reason: %s", v.Bin, err), false
+	}
+	match := v.regex.FindSubmatch(res.Output)
+	if match == nil {
+		return fmt.Sprintf("no version found in the output of '%s'", v.Bin), false
+	}
+	raw := string(match[0])
+	if len(match) > 1 {
+		raw = string(match[1])
+	}
+	version, err := parseVersion(raw)
+	if err != nil {
+		return fmt.Sprintf("unreadable version '%s' of '%s'", raw, v.Bin), false
+	}
+	for _, c := range v.constraints {
+		if !c.satisfies(version) {
+			return fmt.Sprintf("version '%s' of '%s' not satisfying '%s'", raw, v.Bin, v.Constraint), false
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,74 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package triggers
+
+import (
+	"fmt"
+	"os"
+)
+
+// When contains the conditions for running a single Bin, so that one trigger may adapt its bins to
+// the Scope. Every condition which is set must be satisfied, otherwise the bin is Skipped.
+//
+// When is only considered for the bins of a trigger which is already running, after the Skip of the
+// whole trigger. Unlike Skip, it is not bypassed by forcing the trigger to run.
+type When struct {
+	Chroot    bool `toml:"chroot,omitempty"`
+	Live      bool `toml:"live,omitempty"`
+	NotChroot bool `toml:"not_chroot,omitempty"`
+	NotLive   bool `toml:"not_live,omitempty"`
+	// Env lists the variables which must be set to a non-empty value, in the Env of the trigger or
+	// the inherited environment
+	Env []string `toml:"env"`
+}
+
+// Validate rejects conditions which can never all be satisfied
+func (w *When) Validate() error {
+	switch {
+	case w == nil:
+	case w.Chroot && w.NotChroot:
+		return fmt.Errorf("when.chroot and when.not_chroot can never both be satisfied")
+	case w.Live && w.NotLive:
+		return fmt.Errorf("when.live and when.not_live can never both be satisfied")
+	}
+	return nil
+}
+
+// Reason checks if the Scope and environment satisfy all of the conditions, or explains why not
+func (w *When) Reason(s Scope, env map[string]string) (reason string, ok bool) {
+	if w == nil {
+		return "", true
+	}
+	switch {
+	case w.Chroot && !s.Chroot:
+		return "not running in a chroot", false
+	case w.Live && !s.Live:
+		return "not running from a live medium", false
+	case w.NotChroot && s.Chroot:
+		return "running in a chroot", false
+	case w.NotLive && s.Live:
+		return "running from a live medium", false
+	}
+	for _, k := range w.Env {
+		v, found := env[k]
+		if !found {
+			v = os.Getenv(k)
+		}
+		if len(v) == 0 {
+			return fmt.Sprintf("env '%s' not being set", k), false
+		}
+	}
+	return "", true
+}
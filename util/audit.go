@@ -0,0 +1,44 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// AuditPath finds the problems with the ownership and permissions of a path which is trusted to
+// be run as root, allowing it to be owned by any of the owners
+func AuditPath(path string, owners ...uint32) (problems []string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.Mode().Perm()&0002 != 0 {
+		problems = append(problems, fmt.Sprintf("world-writable (%s)", info.Mode().Perm()))
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	for _, owner := range owners {
+		if stat.Uid == owner {
+			return
+		}
+	}
+	problems = append(problems, fmt.Sprintf("owned by uid %d", stat.Uid))
+	return
+}
@@ -0,0 +1,73 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// CgroupRoot is the mount point of the unified (v2) cgroup hierarchy
+const CgroupRoot = "/sys/fs/cgroup"
+
+// cgroupParent is the cgroup under which all usysconf cgroups are created
+var cgroupParent = filepath.Join(CgroupRoot, "usysconf")
+
+// NewCgroup creates (or reuses) a usysconf cgroup and applies the controller settings to it
+func NewCgroup(name string, settings map[string]string) (path string, err error) {
+	if os.Geteuid() != 0 {
+		err = fmt.Errorf("cgroups require root privileges")
+		return
+	}
+	if _, err = os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers")); err != nil {
+		err = fmt.Errorf("unified cgroup hierarchy not available")
+		return
+	}
+	// Enable the controllers for the children of the root and usysconf cgroups
+	if err = os.MkdirAll(cgroupParent, 0755); err != nil {
+		return
+	}
+	for _, dir := range []string{CgroupRoot, cgroupParent} {
+		if err = writeCgroup(dir, "cgroup.subtree_control", "+cpu +cpuset"); err != nil {
+			return
+		}
+	}
+	path = filepath.Join(cgroupParent, name)
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return
+	}
+	for k, v := range settings {
+		if err = writeCgroup(path, k, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// JoinCgroup moves a running process into a cgroup
+func JoinCgroup(path string, pid int) error {
+	return writeCgroup(path, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// writeCgroup sets the value of a single cgroup file
+func writeCgroup(dir, file, value string) error {
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write '%s' to '%s', reason: %s", value, file, err)
+	}
+	return nil
+}
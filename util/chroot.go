@@ -24,12 +24,22 @@ import (
 	"syscall"
 )
 
+// ChrootMarkers is a list of files which indicate a chroot environment, separated by ':' (Makefile)
+var ChrootMarkers = ""
+
 // IsChroot detects if the current process is running in a chroot environment
 func IsChroot() bool {
 	var raw []byte
 	var root, chroot *syscall.Stat_t
 	var rootDir, chrootDir os.FileInfo
 	var pid int
+	// Check for distribution-specific marker files
+	for _, marker := range filepath.SplitList(ChrootMarkers) {
+		if _, err := os.Stat(marker); err == nil {
+			log.Debugf("Chroot marker '%s' found, assuming chroot.\n", marker)
+			return true
+		}
+	}
 	// Try to check for access to the root partition of PID1 (shell?)
 	_, err := os.Stat("/proc/1/root")
 	if err != nil {
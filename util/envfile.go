@@ -0,0 +1,186 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// envKey matches a valid variable name in an environment file
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ReadEnvFile parses a systemd EnvironmentFile, supporting the same subset of the shell syntax:
+//
+//   - one KEY=VALUE assignment per line, with blank lines and lines starting with '#' or ';' ignored
+//   - whitespace around the key and the value is trimmed, within the value it is kept
+//   - single quotes keep everything within them literally, including newlines
+//   - double quotes also span lines, where a backslash only escapes '"', '\', '$', '`' or a newline
+//   - outside of quotes, a backslash escapes any character, or continues the line before a newline
+//
+// Variables within the values are not expanded, which also matches systemd.
+func ReadEnvFile(path string) (env map[string]string, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	p := envParser{text: string(data), line: 1}
+	env = make(map[string]string)
+	for {
+		p.skipSpace()
+		if p.done() {
+			return
+		}
+		if c := p.text[p.pos]; c == '#' || c == ';' {
+			p.skipLine()
+			continue
+		}
+		line := p.line
+		var key, value string
+		if key, err = p.key(); err == nil {
+			value, err = p.value()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+		env[key] = value
+	}
+}
+
+// envParser tracks the position within an environment file
+type envParser struct {
+	text string
+	pos  int
+	line int
+}
+
+// done checks if the whole file has been parsed
+func (p *envParser) done() bool {
+	return p.pos >= len(p.text)
+}
+
+// next consumes a single character
+func (p *envParser) next() byte {
+	c := p.text[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipSpace consumes any whitespace, including newlines
+func (p *envParser) skipSpace() {
+	for !p.done() && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+		p.next()
+	}
+}
+
+// skipLine consumes the rest of the line, including the newline
+func (p *envParser) skipLine() {
+	for !p.done() && p.next() != '\n' {
+	}
+}
+
+// key consumes the name of a variable and the following '='
+func (p *envParser) key() (string, error) {
+	start := p.pos
+	for !p.done() && p.text[p.pos] != '=' && p.text[p.pos] != '\n' {
+		p.next()
+	}
+	key := strings.TrimSpace(p.text[start:p.pos])
+	if p.done() || p.next() != '=' {
+		return "", fmt.Errorf("missing '=' after '%s'", key)
+	}
+	if !envKey.MatchString(key) {
+		return "", fmt.Errorf("invalid variable name '%s'", key)
+	}
+	return key, nil
+}
+
+// value consumes the value of a variable, up to the end of its line
+func (p *envParser) value() (string, error) {
+	for !p.done() && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.next()
+	}
+	var value strings.Builder
+	// Unquoted whitespace is only kept once something follows it
+	var space strings.Builder
+	for !p.done() {
+		c := p.next()
+		switch c {
+		case '\n':
+			return value.String(), nil
+		case ' ', '\t', '\r':
+			space.WriteByte(c)
+			continue
+		}
+		value.WriteString(space.String())
+		space.Reset()
+		switch c {
+		case '\'':
+			end := strings.IndexByte(p.text[p.pos:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated single quote")
+			}
+			for i := 0; i < end; i++ {
+				value.WriteByte(p.next())
+			}
+			p.next()
+		case '"':
+			if err := p.doubleQuoted(&value); err != nil {
+				return "", err
+			}
+		case '\\':
+			if p.done() {
+				break
+			}
+			if c = p.next(); c != '\n' {
+				value.WriteByte(c)
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return value.String(), nil
+}
+
+// doubleQuoted consumes the rest of a double quoted string into value
+func (p *envParser) doubleQuoted(value *strings.Builder) error {
+	for !p.done() {
+		c := p.next()
+		switch c {
+		case '"':
+			return nil
+		case '\\':
+			if p.done() {
+				break
+			}
+			switch e := p.next(); e {
+			case '\n':
+			case '"', '\\', '$', '`':
+				value.WriteByte(e)
+			default:
+				value.WriteByte(c)
+				value.WriteByte(e)
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return fmt.Errorf("unterminated double quote")
+}
@@ -16,9 +16,11 @@ package util
 
 import (
 	"path/filepath"
+	"sort"
 )
 
 // FilterPaths will process through globbed paths and remove any paths from the resulting slice if they are present in the exclude slice.
+// The resulting paths are always sorted, so that the order does not vary between runs.
 func FilterPaths(include []string, exclude []string) []string {
 	paths := make([]string, 0)
 
@@ -29,7 +31,7 @@ func FilterPaths(include []string, exclude []string) []string {
 			continue
 		}
 
-		ipaths = append(paths, ps...)
+		ipaths = append(ipaths, ps...)
 	}
 
 	epaths := make([]string, 0)
@@ -53,5 +55,6 @@ it:
 		paths = append(paths, ip)
 	}
 
+	sort.Strings(paths)
 	return paths
 }
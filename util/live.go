@@ -17,18 +17,23 @@ package util
 import (
 	log "github.com/DataDrake/waterlog"
 	"os"
+	"path/filepath"
 )
 
+// LiveMarkers is a list of files which indicate a live session, separated by ':' (Makefile)
+var LiveMarkers = "/run/initramfs/livedev"
+
 // IsLive checks is this process is running in a Live install
 func IsLive() bool {
-	var err error
-	if _, err = os.Stat("/run/initramfs/livedev"); err == nil {
-		log.Debugln("Live session detected.")
-		return true
-	}
-	if os.IsNotExist(err) {
-		return false
+	for _, marker := range filepath.SplitList(LiveMarkers) {
+		_, err := os.Stat(marker)
+		if err == nil {
+			log.Debugf("Live session detected by '%s'.\n", marker)
+			return true
+		}
+		if !os.IsNotExist(err) {
+			log.Fatalf("Could not check for live session, reason: %s\n", err)
+		}
 	}
-	log.Fatalf("Could not check for live session, reason: %s\n", err)
 	return false
 }
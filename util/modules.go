@@ -0,0 +1,67 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ModulesPath lists the loaded kernel modules, may be changed for testing
+var ModulesPath = "/proc/modules"
+
+// SysModulePath holds a directory for every module known to the kernel, including those built into
+// it, may be changed for testing
+var SysModulePath = "/sys/module"
+
+// moduleName normalizes the name of a kernel module, which may use '-' and '_' interchangeably
+func moduleName(name string) string {
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// loadedModules reads the names of the modules in ModulesPath
+func loadedModules() map[string]bool {
+	loaded := make(map[string]bool)
+	f, err := os.Open(ModulesPath)
+	if err != nil {
+		return loaded
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			loaded[moduleName(fields[0])] = true
+		}
+	}
+	return loaded
+}
+
+// MissingModules finds the first of the kernel modules which is neither loaded nor built into the
+// kernel, ok is true when all of them are available
+func MissingModules(names []string) (missing string, ok bool) {
+	loaded := loadedModules()
+	for _, name := range names {
+		if loaded[moduleName(name)] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(SysModulePath, moduleName(name))); err == nil {
+			continue
+		}
+		return name, false
+	}
+	return "", true
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+const (
+	// MinOOMScoreAdj protects a process from the OOM killer entirely
+	MinOOMScoreAdj = -1000
+	// MaxOOMScoreAdj makes a process the first to be killed when out of memory
+	MaxOOMScoreAdj = 1000
+)
+
+// SetOOMScoreAdj changes how likely a process is to be killed when the system runs out of memory.
+// Lowering it below the current value requires root privileges.
+func SetOOMScoreAdj(pid, adj int) error {
+	path := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(adj)), 0644); err != nil {
+		return fmt.Errorf("failed to write '%d' to '%s', reason: %s", adj, path, err)
+	}
+	return nil
+}
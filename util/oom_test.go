@@ -0,0 +1,49 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestSetOOMScoreAdj(t *testing.T) {
+	// Raising the score never needs privileges, unlike lowering it
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("failed to start a process to adjust, reason: %s", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	if err := SetOOMScoreAdj(cmd.Process.Pid, 500); err != nil {
+		t.Fatalf("SetOOMScoreAdj: %s", err)
+	}
+	raw, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", cmd.Process.Pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adj := strings.TrimSpace(string(raw)); adj != "500" {
+		t.Errorf("expected the score to be adjusted to 500, got %s", adj)
+	}
+}
+
+func TestSetOOMScoreAdjMissing(t *testing.T) {
+	if err := SetOOMScoreAdj(-1, 500); err == nil {
+		t.Error("expected an error for a process which does not exist")
+	}
+}
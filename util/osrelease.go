@@ -0,0 +1,69 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	log "github.com/DataDrake/waterlog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// OSReleasePath is the location of the os-release file, may be changed for testing along with
+// ResetOSRelease
+var OSReleasePath = "/etc/os-release"
+
+var (
+	osRelease     map[string]string
+	osReleaseOnce sync.Once
+)
+
+// loadOSRelease parses the KEY=VALUE pairs of the os-release file
+func loadOSRelease() {
+	osRelease = make(map[string]string)
+	f, err := os.Open(OSReleasePath)
+	if err != nil {
+		log.Warnf("Failed to read '%s', reason: %s\n", OSReleasePath, err)
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		osRelease[pieces[0]] = strings.Trim(pieces[1], "\"'")
+	}
+}
+
+// OSRelease gets a value from the os-release file, which is only read once
+func OSRelease(key string) (value string, ok bool) {
+	osReleaseOnce.Do(loadOSRelease)
+	value, ok = osRelease[key]
+	return
+}
+
+// ResetOSRelease forgets the values read from the os-release file, so that the next OSRelease reads
+// them again from OSReleasePath. It must not be called while OSRelease may be.
+func ResetOSRelease() {
+	osReleaseOnce = sync.Once{}
+	osRelease = nil
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testOSRelease points OSReleasePath at a temporary file with the content, returning a function to
+// clean up
+func testOSRelease(t *testing.T, content string) func() {
+	dir, err := ioutil.TempDir("", "usysconf-osrelease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := OSReleasePath
+	OSReleasePath = filepath.Join(dir, "os-release")
+	if err = ioutil.WriteFile(OSReleasePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ResetOSRelease()
+	return func() {
+		OSReleasePath = path
+		ResetOSRelease()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOSRelease(t *testing.T) {
+	defer testOSRelease(t, "# Solus\nNAME=\"Solus\"\nVERSION_ID=4.1\n\nPRETTY_NAME='Solus 4.1 Fortitude'\nbroken line\n")()
+	for key, expected := range map[string]string{
+		"NAME":        "Solus",
+		"VERSION_ID":  "4.1",
+		"PRETTY_NAME": "Solus 4.1 Fortitude",
+	} {
+		if value, ok := OSRelease(key); !ok || value != expected {
+			t.Errorf("expected %s to be '%s', got '%s' (%t)", key, expected, value, ok)
+		}
+	}
+	if value, ok := OSRelease("ID_LIKE"); ok {
+		t.Errorf("expected ID_LIKE to be missing, got '%s'", value)
+	}
+}
+
+func TestOSReleaseReset(t *testing.T) {
+	defer testOSRelease(t, "ID=solus\n")()
+	if value, _ := OSRelease("ID"); value != "solus" {
+		t.Fatalf("expected 'solus', got '%s'", value)
+	}
+	// Only read once, until it is reset
+	if err := ioutil.WriteFile(OSReleasePath, []byte("ID=other\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := OSRelease("ID"); value != "solus" {
+		t.Errorf("expected the first value to be kept, got '%s'", value)
+	}
+	ResetOSRelease()
+	if value, _ := OSRelease("ID"); value != "other" {
+		t.Errorf("expected the file to be read again after a reset, got '%s'", value)
+	}
+	OSReleasePath = filepath.Join(filepath.Dir(OSReleasePath), "missing")
+	ResetOSRelease()
+	if _, ok := OSRelease("ID"); ok {
+		t.Error("expected nothing from a missing file")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	log "github.com/DataDrake/waterlog"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// PowerSupplyPath is the directory listing the power supplies of the system
+var PowerSupplyPath = "/sys/class/power_supply"
+
+// readSupply reads a single attribute of a power supply, empty if it is missing
+func readSupply(dir, name string) string {
+	raw, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// OnBattery checks if the system is running from a battery. Systems without a battery, or which
+// cannot report their power supplies, are never considered to be on battery. Supplies with a "Device"
+// scope, i.e. the batteries of a wireless mouse, do not power the system and are ignored.
+func OnBattery() bool {
+	supplies, err := ioutil.ReadDir(PowerSupplyPath)
+	if err != nil {
+		return false
+	}
+	battery := false
+	for _, supply := range supplies {
+		dir := filepath.Join(PowerSupplyPath, supply.Name())
+		if readSupply(dir, "scope") == "Device" {
+			continue
+		}
+		switch readSupply(dir, "type") {
+		case "Mains", "USB":
+			if readSupply(dir, "online") == "1" {
+				return false
+			}
+		case "Battery":
+			battery = true
+		}
+	}
+	if battery {
+		log.Debugln("Running on battery power.")
+	}
+	return battery
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSupplies points PowerSupplyPath at a temporary directory with a power supply for each set
+// of attributes, returning a function to clean up
+func testSupplies(t *testing.T, supplies map[string]map[string]string) func() {
+	dir, err := ioutil.TempDir("", "usysconf-power")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, attrs := range supplies {
+		supply := filepath.Join(dir, name)
+		if err := os.Mkdir(supply, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for attr, value := range attrs {
+			if err := ioutil.WriteFile(filepath.Join(supply, attr), []byte(value+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	prev := PowerSupplyPath
+	PowerSupplyPath = dir
+	return func() {
+		PowerSupplyPath = prev
+		os.RemoveAll(dir)
+	}
+}
+
+func TestOnBattery(t *testing.T) {
+	tests := []struct {
+		name     string
+		supplies map[string]map[string]string
+		expected bool
+	}{
+		{"none", nil, false},
+		{"battery", map[string]map[string]string{
+			"BAT0": {"type": "Battery"},
+		}, true},
+		{"mains online", map[string]map[string]string{
+			"AC":   {"type": "Mains", "online": "1"},
+			"BAT0": {"type": "Battery"},
+		}, false},
+		{"mains offline", map[string]map[string]string{
+			"AC":   {"type": "Mains", "online": "0"},
+			"BAT0": {"type": "Battery"},
+		}, true},
+		{"device battery", map[string]map[string]string{
+			"hidpp_battery_0": {"type": "Battery", "scope": "Device"},
+		}, false},
+		{"device online", map[string]map[string]string{
+			"ucsi-source-psy": {"type": "USB", "online": "1", "scope": "Device"},
+			"BAT0":            {"type": "Battery"},
+		}, true},
+	}
+	for _, test := range tests {
+		cleanup := testSupplies(t, test.supplies)
+		if actual := OnBattery(); actual != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, actual)
+		}
+		cleanup()
+	}
+}
+
+func TestOnBatteryMissing(t *testing.T) {
+	prev := PowerSupplyPath
+	PowerSupplyPath = filepath.Join(os.TempDir(), "usysconf-missing-power")
+	defer func() { PowerSupplyPath = prev }()
+	if OnBattery() {
+		t.Error("expected a system without power supplies not to be on battery")
+	}
+}
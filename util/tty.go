@@ -0,0 +1,98 @@
+// Copyright © 2019-2020 Solus Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IsTerminal checks if a file is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Confirm asks the user a yes or no question on the terminal, defaulting to no. The question is
+// written to stderr, leaving stdout to the report of a run.
+func Confirm(question string) (bool, error) {
+	if !IsTerminal(os.Stdin) {
+		return false, fmt.Errorf("not running interactively")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// Pick asks the user to choose any number of numbered options on the terminal, i.e. "1 3 5-7" or
+// "all", returning the indices of the chosen options in the order they are listed. Like Confirm,
+// the options and question are written to stderr.
+func Pick(question string, options []string) ([]int, error) {
+	if !IsTerminal(os.Stdin) {
+		return nil, fmt.Errorf("not running interactively")
+	}
+	for i, option := range options {
+		fmt.Fprintf(os.Stderr, "%3d) %s\n", i+1, option)
+	}
+	fmt.Fprintf(os.Stderr, "%s (i.e. 1 3 5-7, or all): ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	chosen := make([]bool, len(options))
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		if strings.ToLower(field) == "all" {
+			for i := range chosen {
+				chosen[i] = true
+			}
+			continue
+		}
+		first, last := field, field
+		if dash := strings.Index(field, "-"); dash > 0 {
+			first, last = field[:dash], field[dash+1:]
+		}
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 1 || start > len(options) {
+			return nil, fmt.Errorf("invalid choice '%s'", field)
+		}
+		end, err := strconv.Atoi(last)
+		if err != nil || end < start || end > len(options) {
+			return nil, fmt.Errorf("invalid choice '%s'", field)
+		}
+		for i := start; i <= end; i++ {
+			chosen[i-1] = true
+		}
+	}
+	var indices []int
+	for i, ok := range chosen {
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+	return indices, nil
+}